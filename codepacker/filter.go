@@ -0,0 +1,34 @@
+package codepacker
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchesFilter reports whether relPath matches any of the include/exclude patterns.
+// Patterns without a "/" are matched against every path component, other patterns
+// against the relative path and each of its parent directories.
+func matchesFilter(patterns []string, relPath string) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		pattern = strings.TrimPrefix(pattern, "./")
+
+		if !strings.Contains(pattern, "/") {
+			for _, part := range parts {
+				if matched, _ := path.Match(pattern, part); matched {
+					return true
+				}
+			}
+			continue
+		}
+
+		for i := len(parts); i > 0; i-- {
+			if matched, _ := path.Match(pattern, strings.Join(parts[:i], "/")); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package codepacker
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		relPath  string
+		want     bool
+	}{
+		{"slashless pattern matches file name", []string{"*.go"}, "main.go", true},
+		{"slashless pattern matches at any depth", []string{"*.go"}, "cmd/tool/main.go", true},
+		{"slashless pattern matches directory component", []string{"gen"}, "internal/gen/x.go", true},
+		{"slashless pattern misses", []string{"*.go"}, "main.py", false},
+		{"pattern with slash matches parent directory", []string{"internal/gen"}, "internal/gen/x.go", true},
+		{"pattern with slash is anchored", []string{"internal/gen"}, "pkg/internal/gen/x.go", false},
+		{"pattern with slash and wildcard", []string{"internal/*"}, "internal/a/b.go", true},
+		{"leading ./ and trailing / are ignored", []string{"./internal/"}, "internal/x.go", true},
+		{"any of several patterns", []string{"*.sql", "*.go"}, "db/schema.sql", true},
+		{"no patterns", nil, "main.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(tt.patterns, filepath.FromSlash(tt.relPath)); got != tt.want {
+				t.Errorf("matchesFilter(%q, %q) = %v, want %v", tt.patterns, tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeWinsOverInclude(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"main.go":             "package main\n",
+		"internal/gen/gen.go": "package gen\n",
+		"README.md":           "# readme\n",
+	})
+	p, err := newPacker(Options{
+		InputDirs: []string{dir},
+		Includes:  []string{"*.go"},
+		Excludes:  []string{"internal/gen"},
+		PathStyle: PathStyleRelative,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := p.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, job := range jobs {
+		got = append(got, filepath.ToSlash(job.header))
+	}
+	if want := []string{"main.go"}; !slices.Equal(got, want) {
+		t.Errorf("collected %q, want %q", got, want)
+	}
+}
+
+func TestValidateRejectsDoubleStar(t *testing.T) {
+	if err := (Options{Includes: []string{"src/**/*.go"}}).Validate(); err == nil {
+		t.Error(`Validate() accepted "src/**/*.go"`)
+	}
+	if err := (Options{Excludes: []string{"[a-"}}).Validate(); err == nil {
+		t.Error(`Validate() accepted the malformed pattern "[a-"`)
+	}
+}
+
+func TestValidateKeepsCallerSlices(t *testing.T) {
+	includes := make([]string, 1, 2)
+	includes[0] = "*.go"
+	spare := includes[:2]
+	spare[1] = "untouched"
+
+	if err := (Options{Includes: includes, Excludes: []string{"*_test.go"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if spare[1] != "untouched" {
+		t.Errorf("Validate() wrote %q into the spare capacity of Includes", spare[1])
+	}
+}
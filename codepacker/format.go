@@ -0,0 +1,152 @@
+package codepacker
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Maximum path length varies by OS, adding extra bytes for comment markers and newlines
+// Windows MAX_PATH is 260, Unix typically 4096
+const maxBufferSize = 4096 + 100 // path length + extra space for comments and formatting
+
+// formatBlock renders a file's header and contents in the given output format
+func formatBlock(format string, job packJob, code []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(maxBufferSize + len(code))
+
+	if format == FormatMarkdown {
+		fence := markdownFence(code)
+		buf.WriteString("### ")
+		buf.WriteString(job.header)
+		buf.WriteString("\n\n")
+		buf.WriteString(fence)
+		buf.WriteString(languageFor(job.ext))
+		buf.WriteString("\n")
+		buf.Write(code)
+		// The closing fence has to start on its own line
+		if len(code) == 0 || code[len(code)-1] != '\n' {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(fence)
+		buf.WriteString("\n")
+	} else {
+		buf.WriteString(job.commentStyle.Prepend)
+		buf.WriteString(" ")
+		buf.WriteString(job.header)
+		buf.WriteString(" ")
+		buf.WriteString(job.commentStyle.Append)
+		buf.WriteString("\n")
+		buf.Write(code)
+	}
+	buf.WriteString("\n\n")
+
+	return buf.Bytes()
+}
+
+// numberLines prefixes every line of code with its right-aligned line number
+// and a " | " separator. The width fits the file's line count, and a trailing
+// newline doesn't count as an extra line.
+func numberLines(code []byte) []byte {
+	lines := bytes.SplitAfter(code, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	width := len(strconv.Itoa(len(lines)))
+
+	var buf bytes.Buffer
+	buf.Grow(len(code) + len(lines)*(width+3))
+	for i, line := range lines {
+		fmt.Fprintf(&buf, "%*d | ", width, i+1)
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
+
+// formatDuplicate renders the one-line header written in place of a file whose
+// contents were already emitted under the path original
+func formatDuplicate(format string, style CommentStyle, header, original string) []byte {
+	// Trailing newlines match the spacing after a regular block
+	if format == FormatMarkdown {
+		return []byte("### " + header + "\n\nDuplicate of `" + original + "`.\n\n\n")
+	}
+	return []byte(style.Prepend + " " + header + " (duplicate of " + original + ") " + style.Append + "\n\n\n")
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in code,
+// so that code containing "```" cannot close the block early
+func markdownFence(code []byte) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// headerPath renders the path of a file shown in its header for the given path
+// style. rootName is the name of the input directory from rootNames.
+func headerPath(style, absdir, rootName, relPath string) string {
+	switch style {
+	case PathStyleRelative:
+		return relPath
+	case PathStyleAbsolute:
+		return filepath.Join(absdir, relPath)
+	default:
+		// Add the input directory name as prefix to maintain context
+		return filepath.Join(rootName, relPath)
+	}
+}
+
+// manifestEntry is a packed file as listed by the manifest
+type manifestEntry struct {
+	path        string
+	size        int
+	duplicateOf string // Path of the earlier file with the same content, if deduplicated
+}
+
+// formatManifest renders the list of packed files as a comment block in text
+// format or as a bullet list in markdown format
+func formatManifest(format string, entries []manifestEntry) []byte {
+	total := 0
+	for _, e := range entries {
+		if e.duplicateOf == "" {
+			total += e.size
+		}
+	}
+
+	var sb strings.Builder
+	if format == FormatMarkdown {
+		fmt.Fprintf(&sb, "## Manifest (%d files, %d bytes)\n\n", len(entries), total)
+		for _, e := range entries {
+			if e.duplicateOf != "" {
+				fmt.Fprintf(&sb, "- `%s` (duplicate of `%s`)\n", e.path, e.duplicateOf)
+			} else {
+				fmt.Fprintf(&sb, "- `%s` (%d bytes)\n", e.path, e.size)
+			}
+		}
+	} else {
+		fmt.Fprintf(&sb, "# Manifest (%d files, %d bytes)\n", len(entries), total)
+		for _, e := range entries {
+			if e.duplicateOf != "" {
+				fmt.Fprintf(&sb, "#   %s (duplicate of %s)\n", e.path, e.duplicateOf)
+			} else {
+				fmt.Fprintf(&sb, "#   %s (%d bytes)\n", e.path, e.size)
+			}
+		}
+	}
+	sb.WriteString("\n\n")
+
+	return []byte(sb.String())
+}
@@ -0,0 +1,387 @@
+package codepacker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ignorePattern is a single .gitignore rule
+type ignorePattern struct {
+	pattern string // Glob pattern with any leading "!" and trailing "/" removed
+	negate  bool   // Pattern re-includes paths matched by earlier rules
+	dirOnly bool   // Pattern had a trailing "/" and only matches directories
+	baseDir string // Directory the pattern is relative to
+}
+
+// codepackerIgnoreFile is read from each input directory in addition to .gitignore
+const codepackerIgnoreFile = ".codepackerignore"
+
+// GitIgnore holds the ignore patterns and their base directory
+type GitIgnore struct {
+	patterns []ignorePattern
+	baseDir  string
+	nestedAt int           // Index in patterns where nested .gitignore patterns start, before .codepackerignore
+	scopes   []ignoreScope // Nested .gitignore files of the directories being walked, outermost first
+}
+
+// ignoreScope records the patterns a nested .gitignore file added to GitIgnore.patterns
+type ignoreScope struct {
+	dir   string
+	count int
+}
+
+// LoadGitIgnore loads .gitignore files from the given directory and its parents,
+// followed by the .codepackerignore file in the given directory if there is one
+func LoadGitIgnore(dir string) (*GitIgnore, error) {
+	patterns := make([]ignorePattern, 0)
+
+	// Start from the given directory and move up until we find a .git folder or reach root
+	currentDir := dir
+	for {
+		gitignorePath := filepath.Join(currentDir, ".gitignore")
+		if _, err := os.Stat(gitignorePath); err == nil {
+			filePatterns, err := readIgnoreFile(gitignorePath, currentDir)
+			if err != nil {
+				return nil, err
+			}
+			// Parent rules come first so that rules closer to dir win
+			patterns = append(filePatterns, patterns...)
+		}
+
+		// Check if we're in a git repository
+		if _, err := os.Stat(filepath.Join(currentDir, ".git")); err == nil {
+			// Found the repository root, stop here
+			gi := &GitIgnore{
+				patterns: patterns,
+				baseDir:  currentDir,
+				nestedAt: len(patterns),
+			}
+			return gi, gi.loadCodepackerIgnore(dir)
+		}
+
+		// Move up one directory
+		parentDir := filepath.Dir(currentDir)
+		if parentDir == currentDir {
+			// We've reached the root directory
+			break
+		}
+		currentDir = parentDir
+	}
+
+	// If we didn't find a .git directory, just use the patterns we found (if any)
+	gi := &GitIgnore{
+		patterns: patterns,
+		baseDir:  dir,
+		nestedAt: len(patterns),
+	}
+	return gi, gi.loadCodepackerIgnore(dir)
+}
+
+// EnterDir is called by a walk entering dir. It adds the patterns of
+// dir/.gitignore, if there is one, scoped to dir and its subdirectories the
+// way git applies nested .gitignore files. They take precedence over the
+// .gitignore files of parent directories but not over .codepackerignore or
+// AddIgnoreFile patterns. Patterns of directories the walk has left since
+// are dropped, so only the files on the path to dir are evaluated.
+func (gi *GitIgnore) EnterDir(dir string) error {
+	// Leave the scopes that dir isn't inside of, the walk is done with them
+	for len(gi.scopes) > 0 {
+		top := gi.scopes[len(gi.scopes)-1]
+		if _, _, ok := relativeTo(top.dir, dir); ok {
+			break
+		}
+		end := gi.nestedEnd()
+		gi.patterns = slices.Delete(gi.patterns, end-top.count, end)
+		gi.scopes = gi.scopes[:len(gi.scopes)-1]
+	}
+
+	// .gitignore files up to the input directory are loaded by LoadGitIgnore already
+	for _, scope := range gi.scopes {
+		if scope.dir == dir {
+			return nil
+		}
+	}
+	for _, p := range gi.patterns[:gi.nestedAt] {
+		if p.baseDir == dir {
+			return nil
+		}
+	}
+
+	gitignorePath := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignorePath); err != nil {
+		return nil
+	}
+	patterns, err := readIgnoreFile(gitignorePath, dir)
+	if err != nil {
+		return err
+	}
+	gi.patterns = slices.Insert(gi.patterns, gi.nestedEnd(), patterns...)
+	gi.scopes = append(gi.scopes, ignoreScope{dir: dir, count: len(patterns)})
+	return nil
+}
+
+// nestedEnd returns the index in patterns after the last nested .gitignore pattern
+func (gi *GitIgnore) nestedEnd() int {
+	end := gi.nestedAt
+	for _, scope := range gi.scopes {
+		end += scope.count
+	}
+	return end
+}
+
+// loadCodepackerIgnore adds the patterns of dir/.codepackerignore if the file exists
+func (gi *GitIgnore) loadCodepackerIgnore(dir string) error {
+	path := filepath.Join(dir, codepackerIgnoreFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return gi.AddIgnoreFile(path, dir)
+}
+
+// AddIgnoreFile adds the patterns of an ignore file written in .gitignore syntax,
+// relative to baseDir. They are evaluated after all previously loaded patterns,
+// so they take precedence and can re-include paths with negated patterns.
+func (gi *GitIgnore) AddIgnoreFile(path, baseDir string) error {
+	patterns, err := readIgnoreFile(path, baseDir)
+	if err != nil {
+		return err
+	}
+	gi.patterns = append(gi.patterns, patterns...)
+	return nil
+}
+
+// readIgnoreFile parses the patterns of a single ignore file in declaration order
+func readIgnoreFile(path, baseDir string) ([]ignorePattern, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ignore file: %v", err)
+	}
+	defer file.Close()
+
+	patterns := make([]ignorePattern, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := ignorePattern{pattern: line, baseDir: baseDir}
+		if strings.HasPrefix(line, "!") {
+			p.pattern = line[1:]
+			p.negate = true
+		} else if strings.HasPrefix(line, `\!`) {
+			// Escaped "!" matches a literal leading exclamation mark
+			p.pattern = line[1:]
+		}
+		if strings.HasSuffix(p.pattern, "/") {
+			p.pattern = strings.TrimRight(p.pattern, "/")
+			p.dirOnly = true
+		}
+		if p.pattern != "" {
+			patterns = append(patterns, p)
+		}
+	}
+
+	if scanner.Err() != nil {
+		return nil, fmt.Errorf("error reading %s: %v", filepath.Base(path), scanner.Err())
+	}
+
+	return patterns, nil
+}
+
+// Common directories to ignore even if not in .gitignore
+var commonIgnores = []string{
+	"node_modules",
+	"vendor",
+	"build",
+	"dist",
+	"target",
+	"bin",
+	"obj",
+	".git",
+	".idea",
+	".vscode",
+	".zig-cache",
+	"zig-out",
+	"__pycache__",
+	".pytest_cache",
+	".mypy_cache",
+}
+
+// isCommonIgnore reports whether any path component is a commonly ignored directory
+func isCommonIgnore(pathParts []string) bool {
+	return len(commonIgnoreParts(pathParts)) > 0
+}
+
+// commonIgnoreParts returns the path components that are commonly ignored directories
+func commonIgnoreParts(pathParts []string) []string {
+	var parts []string
+	for _, part := range pathParts {
+		if slices.Contains(commonIgnores, part) {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// mentionsAll reports whether pattern names each of the path components in parts
+func mentionsAll(pattern string, parts []string) bool {
+	for _, part := range parts {
+		if !slices.Contains(strings.Split(pattern, "/"), part) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShouldIgnore checks if a path should be ignored based on gitignore patterns.
+// Patterns are evaluated in declaration order and the last matching pattern wins,
+// so a negated pattern can re-include a path excluded by an earlier one.
+// Common ignores act as the first, lowest-precedence rule, so an explicit
+// negation such as !build/keep.go overrides them too.
+func (gi *GitIgnore) ShouldIgnore(path string) bool {
+	// Convert path to be relative to the base directory
+	relPath, err := filepath.Rel(gi.baseDir, path)
+	if err != nil {
+		return false
+	}
+
+	pathParts := strings.Split(relPath, string(filepath.Separator))
+	ignored := isCommonIgnore(pathParts)
+
+	// Directory-only patterns need to know whether path itself is a directory
+	isDir := func() bool {
+		info, err := os.Lstat(path)
+		return err == nil && info.IsDir()
+	}
+
+	// Check each gitignore pattern, relative to the directory it was loaded from
+	base := gi.baseDir
+	inBase := true
+	for _, p := range gi.patterns {
+		if p.baseDir != base {
+			base = p.baseDir
+			relPath, pathParts, inBase = relativeTo(base, path)
+		}
+		if inBase && p.matches(relPath, pathParts, isDir) {
+			ignored = !p.negate
+		}
+	}
+
+	return ignored
+}
+
+// relativeTo returns path relative to base and split into its components.
+// It reports false if path is not inside base.
+func relativeTo(base, path string) (string, []string, bool) {
+	relPath, err := filepath.Rel(base, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", nil, false
+	}
+	return relPath, strings.Split(relPath, string(filepath.Separator)), true
+}
+
+// CanReinclude reports whether a negated pattern could re-include something below
+// the ignored directory dir, in which case the walk has to descend into it.
+func (gi *GitIgnore) CanReinclude(dir string) bool {
+	relPath, err := filepath.Rel(gi.baseDir, dir)
+	if err != nil {
+		return false
+	}
+
+	// Common ignores like node_modules or .git are large, only descend into them
+	// for negations that name the directory explicitly
+	commonParts := commonIgnoreParts(strings.Split(relPath, string(filepath.Separator)))
+
+	for _, p := range gi.patterns {
+		if !p.negate || !mentionsAll(p.pattern, commonParts) {
+			continue
+		}
+
+		relDir, dirParts, ok := relativeTo(p.baseDir, dir)
+		if !ok {
+			// The pattern may still apply if its base directory is below dir
+			if _, _, below := relativeTo(dir, p.baseDir); below {
+				return true
+			}
+			continue
+		}
+		if relDir == "." {
+			return true
+		}
+
+		// Patterns without a separator or with "**" may match at any depth
+		if !strings.Contains(p.pattern, "/") || strings.Contains(p.pattern, "**") {
+			return true
+		}
+
+		// Otherwise the leading segments of the pattern have to match dir
+		patternParts := strings.Split(strings.TrimPrefix(p.pattern, "/"), "/")
+		if len(patternParts) <= len(dirParts) {
+			continue
+		}
+		prefixMatch := true
+		for i, part := range dirParts {
+			if matched, err := filepath.Match(patternParts[i], part); err != nil || !matched {
+				prefixMatch = false
+				break
+			}
+		}
+		if prefixMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether the pattern matches relPath or one of its parent directories.
+// Like in git, a pattern with a separator is anchored to its base directory.
+// isDir is only consulted for directory-only patterns matching relPath itself.
+func (p ignorePattern) matches(relPath string, pathParts []string, isDir func() bool) bool {
+	// A pattern without a separator matches a name at any depth below its base directory
+	if !strings.Contains(p.pattern, "/") {
+		for i, part := range pathParts {
+			matched, err := filepath.Match(p.pattern, part)
+			if err == nil && matched && (!p.dirOnly || i < len(pathParts)-1 || isDir()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A leading separator only anchors the pattern, relPath has none
+	anchored := strings.TrimPrefix(p.pattern, "/")
+	matched, err := filepath.Match(anchored, relPath)
+	if err == nil && matched && (!p.dirOnly || isDir()) {
+		return true
+	}
+
+	// A pattern matching a parent directory applies to everything below it
+	for i := 1; i < len(pathParts); i++ {
+		parent := filepath.Join(pathParts[:i]...)
+		matched, err := filepath.Match(anchored, parent)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	// Handle directory wildcards (e.g., **/node_modules)
+	if strings.Contains(p.pattern, "**") {
+		pattern := strings.ReplaceAll(p.pattern, "**", "*")
+		for i, part := range pathParts {
+			matched, err := filepath.Match(pattern, part)
+			if err == nil && matched && (!p.dirOnly || i < len(pathParts)-1 || isDir()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,139 @@
+package codepacker
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTree creates the given files below dir, with parent directories as needed.
+// Names ending in "/" create empty directories.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newRepo creates a temporary repository root holding files, so that
+// LoadGitIgnore doesn't pick up .gitignore files from outside it
+func newRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{".git/": ""})
+	writeTree(t, dir, files)
+	return dir
+}
+
+func TestShouldIgnoreNegatesCommonIgnore(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		".gitignore":        "build/\n!build/keep.*\n",
+		"build/keep.go":     "package build\n",
+		"build/drop.go":     "package build\n",
+		"node_modules/a.js": "",
+	})
+	gi, err := LoadGitIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"build", true},
+		{"build/keep.go", false},
+		{"build/drop.go", true},
+		{"node_modules/a.js", true},
+	}
+	for _, tt := range tests {
+		if got := gi.ShouldIgnore(filepath.Join(dir, filepath.FromSlash(tt.path))); got != tt.want {
+			t.Errorf("ShouldIgnore(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !gi.CanReinclude(filepath.Join(dir, "build")) {
+		t.Error("CanReinclude(build) = false, want true for !build/keep.*")
+	}
+	if gi.CanReinclude(filepath.Join(dir, "node_modules")) {
+		t.Error("CanReinclude(node_modules) = true, want false without a negation naming it")
+	}
+}
+
+func TestShouldIgnore(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		".gitignore":          "logs/\nout/\n*.log\n!keep.log\n",
+		"logs/app.txt":        "",
+		"logs/nested/deep.go": "",
+		"out":                 "a file, not a directory",
+		"debug.log":           "",
+		"keep.log":            "",
+		"main.go":             "",
+	})
+	gi, err := LoadGitIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"directory matches dir-only pattern", "logs", true},
+		{"file named like dir-only pattern", "out", false},
+		{"file below ignored directory", "logs/app.txt", true},
+		{"file nested deeper below ignored directory", "logs/nested/deep.go", true},
+		{"file matches pattern", "debug.log", true},
+		{"negation re-includes file", "keep.log", false},
+		{"unmatched file", "main.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gi.ShouldIgnore(filepath.Join(dir, filepath.FromSlash(tt.path))); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldIgnoreAnchoring(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		".gitignore":           "*.tmp\n/top.go\n",
+		"testdata/.gitignore":  "gen.go\n",
+		"gen.go":               "package x\n",
+		"top.go":               "package x\n",
+		"testdata/top.go":      "package testdata\n",
+		"main.go":              "package x\n",
+		"cache/deep/x.tmp.go":  "package deep\n",
+		"cache/deep/y.go":      "package deep\n",
+		"other/gen.go":         "package other\n",
+		"testdata/gen.go":      "package testdata\n",
+		"testdata/sub/gen.go":  "package sub\n",
+		"testdata/sub/keep.go": "package sub\n",
+	})
+	writeTree(t, dir, map[string]string{"cache/deep.tmp/z.go": "package z\n"})
+
+	var out bytes.Buffer
+	if _, err := Pack(Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cache/deep/x.tmp.go", "cache/deep/y.go", "gen.go", "main.go", "other/gen.go", "testdata/sub/keep.go", "testdata/top.go"}
+	if got := headers(out.String()); !slices.Equal(got, want) {
+		t.Errorf("packed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package codepacker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonHeader opens the document written in FormatJSON, the files array follows
+const jsonHeader = "{\n  \"files\": ["
+
+// jsonFile is an entry of the files array in FormatJSON output
+type jsonFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Bytes    int    `json:"bytes"`
+	Content  string `json:"content"`
+}
+
+// jsonDuplicate is written in place of a jsonFile whose content was already emitted
+type jsonDuplicate struct {
+	Path        string `json:"path"`
+	Language    string `json:"language"`
+	Bytes       int    `json:"bytes"`
+	DuplicateOf string `json:"duplicate_of"`
+}
+
+// formatJSONEntry renders an entry of the files array on a line of its own.
+// The separating comma is written by the caller, which knows the entry's position.
+func formatJSONEntry(path string, entry any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\n    ")
+	enc := json.NewEncoder(&buf)
+	// Code is full of <, > and &, keep them readable
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return nil, fmt.Errorf("error encoding %s as JSON: %v", path, err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// formatJSONFooter closes the files array and the document with the totals
+func formatJSONFooter(files, totalBytes int) []byte {
+	return []byte(fmt.Sprintf("\n  ],\n  \"total_files\": %d,\n  \"total_bytes\": %d\n}\n", files, totalBytes))
+}
@@ -0,0 +1,17 @@
+package codepacker
+
+import "testing"
+
+func TestFormatJSONEntry(t *testing.T) {
+	got, err := formatJSONEntry("a.go", jsonFile{Path: "a.go", Language: "go", Bytes: 11, Content: "a < b && c\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n    {\"path\":\"a.go\",\"language\":\"go\",\"bytes\":11,\"content\":\"a < b && c\\n\"}"; string(got) != want {
+		t.Errorf("formatJSONEntry() = %q, want %q", got, want)
+	}
+
+	if _, err := formatJSONEntry("b.go", make(chan int)); err == nil {
+		t.Error("formatJSONEntry() succeeded for a value JSON can't encode, want an error")
+	}
+}
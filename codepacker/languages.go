@@ -0,0 +1,272 @@
+package codepacker
+
+import (
+	"path"
+	"strings"
+)
+
+// CommentStyle defines the structure for comment syntax
+type CommentStyle struct {
+	Prepend string // Opening/starting comment symbol
+	Append  string // Closing comment symbol (if needed)
+}
+
+// FileExtToComment maps file extensions to the comment markers used for file headers
+var FileExtToComment = map[string]CommentStyle{
+	// C and C-like languages
+	".c":   {Prepend: "//", Append: ""},
+	".h":   {Prepend: "//", Append: ""},
+	".cpp": {Prepend: "//", Append: ""},
+	".hpp": {Prepend: "//", Append: ""},
+	".cc":  {Prepend: "//", Append: ""},
+	".hh":  {Prepend: "//", Append: ""},
+	".cxx": {Prepend: "//", Append: ""},
+	".cs":  {Prepend: "//", Append: ""}, // C#
+
+	// Web development
+	".js":   {Prepend: "//", Append: ""},   // JavaScript
+	".jsx":  {Prepend: "//", Append: ""},   // React JSX
+	".ts":   {Prepend: "//", Append: ""},   // TypeScript
+	".tsx":  {Prepend: "//", Append: ""},   // TypeScript React
+	".php":  {Prepend: "//", Append: ""},   // PHP (also supports #)
+	".css":  {Prepend: "/*", Append: "*/"}, // CSS
+	".scss": {Prepend: "//", Append: ""},   // SASS
+	".less": {Prepend: "//", Append: ""},   // LESS
+
+	// System/Shell scripting
+	".sh":   {Prepend: "#", Append: ""}, // Shell script
+	".bash": {Prepend: "#", Append: ""}, // Bash script
+	".zsh":  {Prepend: "#", Append: ""}, // Zsh script
+	".fish": {Prepend: "#", Append: ""}, // Fish script
+	".ksh":  {Prepend: "#", Append: ""}, // Korn shell
+	".ps1":  {Prepend: "#", Append: ""}, // PowerShell
+	".psm1": {Prepend: "#", Append: ""}, // PowerShell module
+
+	// Modern languages
+	".go":    {Prepend: "//", Append: ""}, // Go
+	".rs":    {Prepend: "//", Append: ""}, // Rust
+	".dart":  {Prepend: "//", Append: ""}, // Dart
+	".swift": {Prepend: "//", Append: ""}, // Swift
+	".kt":    {Prepend: "//", Append: ""}, // Kotlin
+	".scala": {Prepend: "//", Append: ""}, // Scala
+	".zig":   {Prepend: "//", Append: ""},
+
+	// Traditional languages
+	".java":   {Prepend: "//", Append: ""}, // Java
+	".groovy": {Prepend: "//", Append: ""}, // Groovy
+	".rb":     {Prepend: "#", Append: ""},  // Ruby
+	".py":     {Prepend: "#", Append: ""},  // Python
+	".pl":     {Prepend: "#", Append: ""},  // Perl
+	".pm":     {Prepend: "#", Append: ""},  // Perl module
+	".lua":    {Prepend: "--", Append: ""}, // Lua
+	".tcl":    {Prepend: "#", Append: ""},  // Tcl
+
+	// Configuration and markup
+	".yaml": {Prepend: "#", Append: ""},       // YAML
+	".yml":  {Prepend: "#", Append: ""},       // YAML
+	".toml": {Prepend: "#", Append: ""},       // TOML
+	".ini":  {Prepend: ";", Append: ""},       // INI
+	".conf": {Prepend: "#", Append: ""},       // Config files
+	".xml":  {Prepend: "<!--", Append: "-->"}, // XML
+	".html": {Prepend: "<!--", Append: "-->"}, // HTML
+
+	// Database
+	".sql":   {Prepend: "--", Append: ""}, // SQL
+	".psql":  {Prepend: "--", Append: ""}, // PostgreSQL
+	".mysql": {Prepend: "--", Append: ""}, // MySQL
+
+	// Other
+	".r":   {Prepend: "#", Append: ""},    // R
+	".jl":  {Prepend: "#", Append: ""},    // Julia
+	".fs":  {Prepend: "//", Append: ""},   // F#
+	".fsx": {Prepend: "//", Append: ""},   // F# script
+	".f90": {Prepend: "!", Append: ""},    // Fortran
+	".f95": {Prepend: "!", Append: ""},    // Fortran
+	".f":   {Prepend: "!", Append: ""},    // Fortran
+	".elm": {Prepend: "--", Append: ""},   // Elm
+	".ex":  {Prepend: "#", Append: ""},    // Elixir
+	".exs": {Prepend: "#", Append: ""},    // Elixir script
+	".erl": {Prepend: "%", Append: ""},    // Erlang
+	".hrl": {Prepend: "%", Append: ""},    // Erlang header
+	".hs":  {Prepend: "--", Append: ""},   // Haskell
+	".lhs": {Prepend: "--", Append: ""},   // Literate Haskell
+	".ml":  {Prepend: "(*", Append: "*)"}, // OCaml
+	".mli": {Prepend: "(*", Append: "*)"}, // OCaml interface
+	".v":   {Prepend: "//", Append: ""},   // Verilog
+	".vh":  {Prepend: "//", Append: ""},   // Verilog header
+	".vhd": {Prepend: "--", Append: ""},   // VHDL
+}
+
+// languageFor returns the markdown language tag for ext, falling back to the
+// extension itself for extensions added through Options.CommentStyles
+func languageFor(ext string) string {
+	if lang, ok := FileExtToLanguage[ext]; ok {
+		return lang
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// FileExtToLanguage maps file extensions to the language tag used in markdown code fences
+var FileExtToLanguage = map[string]string{
+	// C and C-like languages
+	".c":   "c",
+	".h":   "c",
+	".cpp": "cpp",
+	".hpp": "cpp",
+	".cc":  "cpp",
+	".hh":  "cpp",
+	".cxx": "cpp",
+	".cs":  "csharp",
+
+	// Web development
+	".js":   "javascript",
+	".jsx":  "jsx",
+	".ts":   "typescript",
+	".tsx":  "tsx",
+	".php":  "php",
+	".css":  "css",
+	".scss": "scss",
+	".less": "less",
+
+	// System/Shell scripting
+	".sh":   "sh",
+	".bash": "bash",
+	".zsh":  "zsh",
+	".fish": "fish",
+	".ksh":  "sh",
+	".ps1":  "powershell",
+	".psm1": "powershell",
+
+	// Modern languages
+	".go":    "go",
+	".rs":    "rust",
+	".dart":  "dart",
+	".swift": "swift",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".zig":   "zig",
+
+	// Traditional languages
+	".java":   "java",
+	".groovy": "groovy",
+	".rb":     "ruby",
+	".py":     "python",
+	".pl":     "perl",
+	".pm":     "perl",
+	".lua":    "lua",
+	".tcl":    "tcl",
+
+	// Configuration and markup
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+	".ini":  "ini",
+	".conf": "conf",
+	".xml":  "xml",
+	".html": "html",
+
+	// Database
+	".sql":   "sql",
+	".psql":  "sql",
+	".mysql": "sql",
+
+	// Other
+	".r":   "r",
+	".jl":  "julia",
+	".fs":  "fsharp",
+	".fsx": "fsharp",
+	".f90": "fortran",
+	".f95": "fortran",
+	".f":   "fortran",
+	".elm": "elm",
+	".ex":  "elixir",
+	".exs": "elixir",
+	".erl": "erlang",
+	".hrl": "erlang",
+	".hs":  "haskell",
+	".lhs": "haskell",
+	".ml":  "ocaml",
+	".mli": "ocaml",
+	".v":   "verilog",
+	".vh":  "verilog",
+	".vhd": "vhdl",
+}
+
+// InterpreterToExt maps script interpreters named in a shebang line to the
+// extension whose comment style and language tag the script gets. Version
+// suffixes such as python3.11 are removed before the lookup.
+var InterpreterToExt = map[string]string{
+	"sh":         ".sh",
+	"dash":       ".sh",
+	"ash":        ".sh",
+	"bash":       ".bash",
+	"zsh":        ".zsh",
+	"ksh":        ".ksh",
+	"fish":       ".fish",
+	"pwsh":       ".ps1",
+	"python":     ".py",
+	"pypy":       ".py",
+	"ruby":       ".rb",
+	"perl":       ".pl",
+	"php":        ".php",
+	"lua":        ".lua",
+	"luajit":     ".lua",
+	"tclsh":      ".tcl",
+	"wish":       ".tcl",
+	"node":       ".js",
+	"nodejs":     ".js",
+	"deno":       ".ts",
+	"ts-node":    ".ts",
+	"tsx":        ".ts",
+	"Rscript":    ".r",
+	"julia":      ".jl",
+	"elixir":     ".exs",
+	"groovy":     ".groovy",
+	"escript":    ".erl",
+	"runhaskell": ".hs",
+}
+
+// shebangInterpreter returns the interpreter named in a shebang line, looking
+// through /usr/bin/env and its options. It returns "" if firstLine isn't a shebang.
+func shebangInterpreter(firstLine string) string {
+	cmd, ok := strings.CutPrefix(strings.TrimSpace(firstLine), "#!")
+	if !ok {
+		return ""
+	}
+
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's options and variable assignments, as in "env -S VAR=1 python3 -u"
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+	return interpreter
+}
+
+// detectByShebang returns the extension of the language a script is written
+// in, judging by the interpreter in its shebang line. It reports false if
+// firstLine isn't a shebang or names an interpreter not in InterpreterToExt.
+// It returns an extension rather than a CommentStyle because the extension
+// stands in for the file's own: it selects the comment style, including one
+// overridden through Options.CommentStyles, as well as the markdown and JSON
+// language tag and the multi-line strings that comment stripping protects.
+func detectByShebang(firstLine string) (string, bool) {
+	interpreter := shebangInterpreter(firstLine)
+	if interpreter == "" {
+		return "", false
+	}
+	if ext, ok := InterpreterToExt[interpreter]; ok {
+		return ext, true
+	}
+	ext, ok := InterpreterToExt[strings.TrimRight(interpreter, "0123456789.")]
+	return ext, ok
+}
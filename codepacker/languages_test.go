@@ -0,0 +1,51 @@
+package codepacker
+
+import "testing"
+
+func TestDetectByShebang(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{"#!/bin/sh", ".sh", true},
+		{"#!/bin/bash", ".bash", true},
+		{"#! /bin/sh -e", ".sh", true},
+		{"#!/usr/bin/env python3", ".py", true},
+		{"#!/usr/bin/env -S python3.11 -u", ".py", true},
+		{"#!/usr/bin/env -S PYTHONPATH=lib python3", ".py", true},
+		{"#!/usr/bin/env node", ".js", true},
+		{"#!/usr/local/bin/ruby2.7", ".rb", true},
+		{"#!/usr/bin/perl -w", ".pl", true},
+		{"#!/usr/bin/unknown-interpreter", "", false},
+		{"#!/usr/bin/env -S", "", false},
+		{"#!", "", false},
+		{"package main", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := detectByShebang(tt.line)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("detectByShebang(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"#!/usr/bin/python3", "python3"},
+		{"#!/usr/bin/env -S VAR=1 python3 -u", "python3"},
+		{"#!/usr/bin/unknown-interpreter --flag", "unknown-interpreter"},
+		{"# comment", ""},
+	}
+	for _, tt := range tests {
+		if got := shebangInterpreter(tt.line); got != tt.want {
+			t.Errorf("shebangInterpreter(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
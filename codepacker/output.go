@@ -0,0 +1,153 @@
+package codepacker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// blockWriter receives the output in whole blocks (the tree view, the manifest
+// and one block per file) so that no block is ever split between two outputs.
+// name identifies the block in diagnostics.
+type blockWriter interface {
+	writeBlock(name string, b []byte) error
+}
+
+// streamWriter writes all blocks to a single writer
+type streamWriter struct {
+	w io.Writer
+}
+
+func (s *streamWriter) writeBlock(name string, b []byte) error {
+	if _, err := s.w.Write(b); err != nil {
+		return fmt.Errorf("error writing to output file: %v", err)
+	}
+	return nil
+}
+
+// spoolWriter buffers blocks in a temporary file and remembers their
+// boundaries, so they can be replayed after something that has to come first
+type spoolWriter struct {
+	f     *os.File
+	bw    *bufio.Writer
+	names []string
+	sizes []int
+}
+
+func newSpoolWriter() (*spoolWriter, error) {
+	f, err := os.CreateTemp("", "codepacker-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %v", err)
+	}
+	return &spoolWriter{f: f, bw: bufio.NewWriter(f)}, nil
+}
+
+func (s *spoolWriter) writeBlock(name string, b []byte) error {
+	if _, err := s.bw.Write(b); err != nil {
+		return fmt.Errorf("error writing temporary file: %v", err)
+	}
+	s.names = append(s.names, name)
+	s.sizes = append(s.sizes, len(b))
+	return nil
+}
+
+// replay writes the spooled blocks to dst in their original order
+func (s *spoolWriter) replay(dst blockWriter) error {
+	if err := s.bw.Flush(); err != nil {
+		return fmt.Errorf("error writing temporary file: %v", err)
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error reading temporary file: %v", err)
+	}
+
+	r := bufio.NewReader(s.f)
+	var buf []byte
+	for i, size := range s.sizes {
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("error reading temporary file: %v", err)
+		}
+		if err := dst.writeBlock(s.names[i], buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close removes the temporary file
+func (s *spoolWriter) close() {
+	s.f.Close()
+	os.Remove(s.f.Name())
+}
+
+// splitWriter writes blocks to numbered parts, starting a new part whenever the
+// next block would push the current one past max. A block larger than max on
+// its own gets a part of its own.
+type splitWriter struct {
+	p      *packer
+	max    int64
+	create func(part int) (io.WriteCloser, error)
+
+	part int   // Number of the current part, starting at 1
+	size int64 // Bytes written to the current part
+	cur  io.WriteCloser
+	bw   *bufio.Writer
+}
+
+func (s *splitWriter) writeBlock(name string, b []byte) error {
+	n := int64(len(b))
+	if s.cur == nil || (s.size > 0 && s.size+n > s.max) {
+		if err := s.next(); err != nil {
+			return err
+		}
+	}
+	if n > s.max {
+		s.p.warnf("Warning: %s (%d bytes) exceeds the maximum output size of %d bytes, writing it to part %d on its own\n", name, n, s.max, s.part)
+	}
+
+	if _, err := s.bw.Write(b); err != nil {
+		return fmt.Errorf("error writing to output part %d: %v", s.part, err)
+	}
+	s.size += n
+	return nil
+}
+
+// next closes the current part and opens the following one
+func (s *splitWriter) next() error {
+	if err := s.close(); err != nil {
+		return err
+	}
+
+	s.part++
+	if s.part > 1 {
+		s.p.logf("Output size limit reached, starting part %d\n", s.part)
+	}
+	cur, err := s.create(s.part)
+	if err != nil {
+		return err
+	}
+	s.cur = cur
+	s.bw = bufio.NewWriter(cur)
+	s.size = 0
+	return nil
+}
+
+// close flushes and closes the current part, if any
+func (s *splitWriter) close() error {
+	if s.cur == nil {
+		return nil
+	}
+	err := s.bw.Flush()
+	if closeErr := s.cur.Close(); err == nil {
+		err = closeErr
+	}
+	s.cur = nil
+	if err != nil {
+		return fmt.Errorf("error writing to output part %d: %v", s.part, err)
+	}
+	return nil
+}
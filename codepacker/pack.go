@@ -0,0 +1,717 @@
+// Package codepacker concatenates the source code files of one or more
+// directories into a single document, marking every file with a header in
+// the comment syntax of its language.
+package codepacker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Output formats supported by Options.Format
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// Source encodings supported by Options.Transcode
+const (
+	TranscodeUTF16  = "utf16"
+	TranscodeLatin1 = "latin1"
+)
+
+// Path styles supported by Options.PathStyle
+const (
+	PathStyleRelative = "relative"
+	PathStyleWithRoot = "with-root"
+	PathStyleAbsolute = "absolute"
+)
+
+// Options configures a Pack run. The zero value packs the current directory
+// in text format with the built-in extension map.
+type Options struct {
+	InputDirs      []string                // Directories to pack, "." if empty
+	Files          []string                // If non-nil, pack exactly these files in this order instead of walking InputDirs
+	SortFiles      bool                    // Sort Files by header path, like a walk would
+	NoIgnore       bool                    // Don't apply ignore rules to Files
+	IgnoreFile     string                  // Extra ignore file in .gitignore syntax, applied to every input directory
+	Includes       []string                // Only pack files matching one of these glob patterns
+	Excludes       []string                // Never pack files matching one of these glob patterns, wins over Includes
+	Format         string                  // FormatText (default), FormatMarkdown or FormatJSON
+	PathStyle      string                  // PathStyleWithRoot (default), PathStyleRelative or PathStyleAbsolute
+	Manifest       bool                    // Start the output with a list of the packed files
+	DryRun         bool                    // Only list the files that would be packed, with their sizes and a total
+	MaxFileSize    int64                   // Skip files larger than this many bytes, 0 means unlimited
+	FollowSymlinks bool                    // Pack the targets of symlinks instead of skipping them
+	DetectShebang  bool                    // Recognize scripts without a known extension by their shebang line
+	AllowBinary    bool                    // Pack files even if their content looks binary or isn't valid UTF-8
+	Transcode      string                  // Convert files from TranscodeUTF16 (with a byte order mark) or TranscodeLatin1 to UTF-8
+	StripComments  bool                    // Drop full-line comments from file contents
+	StripBlank     bool                    // Collapse consecutive blank lines in file contents
+	LineNumbers    bool                    // Prefix every line of file contents with its line number
+	Dedupe         bool                    // Replace files whose content was already emitted with a one-line reference
+	Jobs           int                     // Number of files read concurrently, runtime.NumCPU() if 0
+	CommentStyles  map[string]CommentStyle // Extension to comment markers, FileExtToComment if nil
+	Log            io.Writer               // Receives verbose diagnostics if set
+	Warn           io.Writer               // Receives warnings if set, such as an oversized PackSplit part
+}
+
+// Stats summarizes what a Pack or PackSplit run packed
+type Stats struct {
+	Files int // Packed files, including files replaced by a reference to a duplicate
+	Bytes int // Total size of the packed file contents, counting duplicated content once
+	Parts int // Number of parts written by PackSplit
+}
+
+// Validate checks the options for unsupported values
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatText, FormatMarkdown, FormatJSON:
+	default:
+		return fmt.Errorf("unknown output format %q, use %q, %q or %q", o.Format, FormatText, FormatMarkdown, FormatJSON)
+	}
+	if o.Format == FormatJSON && o.Manifest {
+		return fmt.Errorf("the manifest is not supported with the %q format, its files array and totals already list every file", FormatJSON)
+	}
+
+	switch o.PathStyle {
+	case "", PathStyleRelative, PathStyleWithRoot, PathStyleAbsolute:
+	default:
+		return fmt.Errorf("unknown path style %q, use %q, %q or %q", o.PathStyle, PathStyleRelative, PathStyleWithRoot, PathStyleAbsolute)
+	}
+
+	switch o.Transcode {
+	case "", TranscodeUTF16, TranscodeLatin1:
+	default:
+		return fmt.Errorf("unknown source encoding %q, use %q or %q", o.Transcode, TranscodeUTF16, TranscodeLatin1)
+	}
+
+	if o.Jobs < 0 {
+		return fmt.Errorf("invalid number of jobs %d", o.Jobs)
+	}
+	if o.MaxFileSize < 0 {
+		return fmt.Errorf("invalid maximum file size %d", o.MaxFileSize)
+	}
+
+	for _, pattern := range slices.Concat(o.Includes, o.Excludes) {
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid filter pattern %q: %v", pattern, err)
+		}
+		// path.Match would treat "**" like "*" and silently match a single directory only
+		if strings.Contains(pattern, "**") {
+			return fmt.Errorf("invalid filter pattern %q: \"**\" is not supported, a pattern with \"/\" already matches everything below the directories it matches", pattern)
+		}
+	}
+
+	return nil
+}
+
+// Pack walks the input directories and writes a tree view of them followed by
+// every qualifying code file to w, sorted by the path shown in its header.
+// With Options.Files set, only the listed files are packed, in the given order,
+// and the tree view only shows them.
+// With Options.DryRun set, w instead receives one line per file that would be
+// packed and a closing line with the file count and total size.
+// The returned Stats tell how many files were packed, which may be none.
+func Pack(opts Options, w io.Writer) (Stats, error) {
+	p, err := newPacker(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := p.pack(&streamWriter{w: bw}); err != nil {
+		return Stats{}, err
+	}
+	if err := bw.Flush(); err != nil {
+		return Stats{}, fmt.Errorf("error writing to output file: %v", err)
+	}
+	return p.stats, nil
+}
+
+// PackSplit works like Pack but spreads the output over numbered parts of at
+// most maxSize bytes each. create is called with part numbers starting at 1
+// whenever a new part is needed, and every part is closed before the next one
+// is created. A file's block is never split between parts: a new part is
+// started when the next block would exceed maxSize, and a block larger than
+// maxSize on its own is written to a part of its own with a warning.
+func PackSplit(opts Options, maxSize int64, create func(part int) (io.WriteCloser, error)) (Stats, error) {
+	if maxSize <= 0 {
+		return Stats{}, fmt.Errorf("invalid maximum output size %d", maxSize)
+	}
+	if opts.Format == FormatJSON {
+		return Stats{}, fmt.Errorf("the %q format can't be split into parts", FormatJSON)
+	}
+	p, err := newPacker(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	sw := &splitWriter{p: p, max: maxSize, create: create}
+	err = p.pack(sw)
+	if closeErr := sw.close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Stats{}, err
+	}
+	p.stats.Parts = sw.part
+	return p.stats, nil
+}
+
+// packer holds the state of a single Pack run
+type packer struct {
+	opts       Options
+	roots      []string     // Absolute input directories
+	rootNames  []string     // Unique name of each root for PathStyleWithRoot headers
+	gitignores []*GitIgnore // Ignore rules of each root
+	stats      Stats        // Set once all files are written
+}
+
+// newPacker validates opts, fills in defaults and loads the ignore rules of every input directory
+func newPacker(opts Options) (*packer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+	if opts.PathStyle == "" {
+		opts.PathStyle = PathStyleWithRoot
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	if opts.CommentStyles == nil {
+		opts.CommentStyles = FileExtToComment
+	}
+	if len(opts.InputDirs) == 0 {
+		opts.InputDirs = []string{"."}
+	}
+
+	p := &packer{opts: opts}
+
+	// Clean and resolve the input directory paths
+	absdirs := make([]string, 0, len(opts.InputDirs))
+	for _, indir := range opts.InputDirs {
+		cleanInDir := filepath.Clean(indir)
+		absdir, err := filepath.Abs(cleanInDir)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving input directory path: %v", err)
+		}
+		absdirs = append(absdirs, absdir)
+	}
+	p.roots = p.dedupeRoots(absdirs)
+	p.rootNames = rootNames(p.roots)
+
+	// Load gitignore patterns for every input directory
+	for _, absdir := range p.roots {
+		p.logf("Input directory: %s\n", absdir)
+
+		gitignore, err := LoadGitIgnore(absdir)
+		if err == nil && opts.IgnoreFile != "" {
+			// Patterns of the extra ignore file are relative to each input directory
+			err = gitignore.AddIgnoreFile(opts.IgnoreFile, absdir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error loading ignore rules: %v", err)
+		}
+		p.gitignores = append(p.gitignores, gitignore)
+	}
+
+	return p, nil
+}
+
+// logf writes a verbose diagnostic message if logging is enabled
+func (p *packer) logf(format string, args ...any) {
+	if p.opts.Log != nil {
+		fmt.Fprintf(p.opts.Log, format, args...)
+	}
+}
+
+// warnf writes a warning if warnings are enabled
+func (p *packer) warnf(format string, args ...any) {
+	if p.opts.Warn != nil {
+		fmt.Fprintf(p.opts.Warn, format, args...)
+	}
+}
+
+// pack writes the tree view, the optional manifest and all file blocks to dst
+func (p *packer) pack(dst blockWriter) error {
+	// The manifest has to list exactly the files that end up in the output, which
+	// is only known after all of them are read. Spool everything after it to a
+	// temporary file so memory use stays bounded.
+	out := dst
+	var spool *spoolWriter
+	if p.opts.Manifest && !p.opts.DryRun {
+		var err error
+		spool, err = newSpoolWriter()
+		if err != nil {
+			return err
+		}
+		defer spool.close()
+		out = spool
+	}
+
+	var files []packJob
+	if p.opts.Files != nil {
+		files = p.collectList()
+		if p.opts.SortFiles {
+			sortJobs(files)
+		}
+	} else {
+		var err error
+		files, err = p.collect()
+		if err != nil {
+			return fmt.Errorf("error walking directory: %v", err)
+		}
+		sortJobs(files)
+	}
+
+	// JSON output has no tree view, only the files array and the totals
+	if p.opts.Format == FormatJSON && !p.opts.DryRun {
+		if err := out.writeBlock("json header", []byte(jsonHeader)); err != nil {
+			return err
+		}
+	}
+
+	// Generate and write tree view
+	if p.opts.Format != FormatJSON && !p.opts.DryRun {
+		var treeView string
+		if p.opts.Files != nil {
+			paths := make([]string, len(files))
+			for i, job := range files {
+				paths[i] = job.path
+			}
+			treeView = generateFileTreeView(p.roots, paths)
+		} else {
+			var err error
+			treeView, err = generateTreeView(p.roots, p.gitignores, p.opts.FollowSymlinks)
+			if err != nil {
+				return fmt.Errorf("error generating tree view: %v", err)
+			}
+		}
+		if p.opts.Format == FormatMarkdown {
+			// Keep the tree drawing intact when the markdown is rendered
+			treeView = "```\n" + treeView + "```"
+		}
+		if err := out.writeBlock("tree view", []byte(treeView+"\n\n")); err != nil {
+			return err
+		}
+	}
+
+	submitAll := func(submit func(packJob) error) error {
+		for _, job := range files {
+			if err := submit(job); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var packed []manifestEntry
+	emitted := make(map[[32]byte]string) // Content hash to the path it was first emitted under
+	emit := func(res packResult) error {
+		if res.skip != "" {
+			p.logf("Skipping (%s): %s\n", res.skip, res.path)
+			return nil
+		}
+
+		// Separate JSON entries, the first one follows the opening bracket
+		if p.opts.Format == FormatJSON && !p.opts.DryRun && len(packed) > 0 {
+			if err := out.writeBlock("separator", []byte(",")); err != nil {
+				return err
+			}
+		}
+
+		// Results arrive in output order, so the first occurrence is always the one kept
+		if p.opts.Dedupe {
+			if original, ok := emitted[res.hash]; ok {
+				p.logf("Skipping (duplicate of %s): %s\n", original, res.path)
+				packed = append(packed, manifestEntry{path: res.header, size: res.size, duplicateOf: original})
+				if p.opts.DryRun {
+					return out.writeBlock(res.header, []byte(fmt.Sprintf("%s (duplicate of %s)\n", res.header, original)))
+				}
+				if p.opts.Format == FormatJSON {
+					block, err := formatJSONEntry(res.path, jsonDuplicate{
+						Path:        filepath.ToSlash(res.header),
+						Language:    languageFor(res.ext),
+						Bytes:       res.size,
+						DuplicateOf: filepath.ToSlash(original),
+					})
+					if err != nil {
+						return err
+					}
+					return out.writeBlock(res.header, block)
+				}
+				return out.writeBlock(res.header, formatDuplicate(p.opts.Format, res.commentStyle, res.header, original))
+			}
+			emitted[res.hash] = res.header
+		}
+
+		packed = append(packed, manifestEntry{path: res.header, size: res.size})
+		if p.opts.DryRun {
+			return out.writeBlock(res.header, []byte(fmt.Sprintf("%s (%d bytes)\n", res.header, res.size)))
+		}
+
+		p.logf("Processing: %s\n", res.path)
+		return out.writeBlock(res.header, res.block)
+	}
+
+	if err := runPool(p.opts.Jobs, submitAll, p.process, emit); err != nil {
+		return err
+	}
+
+	// Duplicates don't add to the total size, their content isn't repeated
+	total := 0
+	for _, e := range packed {
+		if e.duplicateOf == "" {
+			total += e.size
+		}
+	}
+	p.stats = Stats{Files: len(packed), Bytes: total}
+
+	if p.opts.DryRun {
+		return out.writeBlock("summary", []byte(fmt.Sprintf("%d files, %d bytes would be packed\n", len(packed), total)))
+	}
+	if p.opts.Format == FormatJSON {
+		return out.writeBlock("json footer", formatJSONFooter(len(packed), total))
+	}
+
+	if spool != nil {
+		if err := dst.writeBlock("manifest", formatManifest(p.opts.Format, packed)); err != nil {
+			return err
+		}
+		return spool.replay(dst)
+	}
+	return nil
+}
+
+// collect walks every root and returns the files that pass the ignore rules,
+// the include/exclude filters, the extension lookup and the size limit.
+// Reading and formatting happens later in the worker pool.
+func (p *packer) collect() ([]packJob, error) {
+	var files []packJob
+
+	for i, absdir := range p.roots {
+		gitignore := p.gitignores[i]
+
+		onRevisit := func(path, target string, inRoot bool) {
+			switch {
+			case inRoot && gitignore.ShouldIgnore(target) && !gitignore.CanReinclude(target):
+				// The walk prunes the target, so its contents aren't packed at all
+				p.logf("Skipping (symlink to ignored directory %s, not followed): %s\n", target, path)
+			case inRoot:
+				p.logf("Skipping (symlink to %s, packed under its own path): %s\n", target, path)
+			default:
+				p.logf("Skipping (symlink to %s, walked already): %s\n", target, path)
+			}
+		}
+		err := walk(absdir, p.opts.FollowSymlinks, onRevisit, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Skip .gitignore files
+			if info.Name() == ".gitignore" {
+				return nil
+			}
+
+			// Check if path should be ignored based on gitignore rules
+			if gitignore.ShouldIgnore(path) {
+				if info.IsDir() && !gitignore.CanReinclude(path) {
+					return filepath.SkipDir
+				}
+				if !info.IsDir() {
+					p.logf("Skipping (ignored): %s\n", path)
+					return nil
+				}
+			}
+
+			if info.IsDir() {
+				// A .gitignore in the directory applies to everything below it
+				return gitignore.EnterDir(path)
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				return nil
+			}
+
+			// Calculate path relative to indir, keep the directory structure
+			relPath, err := filepath.Rel(absdir, path)
+			if err != nil {
+				return fmt.Errorf("error getting relative path: %v", err)
+			}
+
+			job, skip := p.newJob(i, relPath, info)
+			if skip != "" {
+				p.logf("Skipping (%s): %s\n", skip, path)
+				return nil
+			}
+			files = append(files, job)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// collectList returns the jobs for Options.Files in the given order. Paths
+// that are missing, directories, outside every input directory or not code
+// files are reported as warnings and skipped, so one bad entry doesn't abort
+// the run.
+func (p *packer) collectList() []packJob {
+	var files []packJob
+	seen := make(map[string]bool)
+
+	for _, file := range p.opts.Files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			p.warnf("Warning: skipping %s: %v\n", file, err)
+			continue
+		}
+		if seen[path] {
+			p.logf("Skipping (listed twice): %s\n", path)
+			continue
+		}
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				p.warnf("Warning: skipping %s: no such file\n", file)
+			} else {
+				p.warnf("Warning: skipping %s: %v\n", file, err)
+			}
+			continue
+		}
+		if info.IsDir() {
+			p.warnf("Warning: skipping %s: is a directory\n", file)
+			continue
+		}
+
+		// Headers and ignore rules are relative to the input directory holding the file
+		root := -1
+		var relPath string
+		for i, absdir := range p.roots {
+			if rel, _, ok := relativeTo(absdir, path); ok && rel != "." {
+				root, relPath = i, rel
+				break
+			}
+		}
+		if root < 0 {
+			p.warnf("Warning: skipping %s: not inside an input directory\n", file)
+			continue
+		}
+
+		if !p.opts.NoIgnore {
+			ignored, err := p.ignoredInList(root, relPath)
+			if err != nil {
+				p.warnf("Warning: skipping %s: %v\n", file, err)
+				continue
+			}
+			if ignored {
+				p.logf("Skipping (ignored): %s\n", path)
+				continue
+			}
+		}
+
+		job, skip := p.newJob(root, relPath, info)
+		if strings.HasPrefix(skip, "not a code file") {
+			p.warnf("Warning: skipping %s: %s\n", file, skip)
+			continue
+		}
+		if skip != "" {
+			p.logf("Skipping (%s): %s\n", skip, path)
+			continue
+		}
+		files = append(files, job)
+	}
+
+	return files
+}
+
+// ignoredInList reports whether the listed file relPath below input directory
+// root is ignored. Without a walk, the nested .gitignore files of the
+// directories on the way to the file have to be entered explicitly.
+func (p *packer) ignoredInList(root int, relPath string) (bool, error) {
+	gitignore := p.gitignores[root]
+	dir := p.roots[root]
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i := 0; ; i++ {
+		if err := gitignore.EnterDir(dir); err != nil {
+			return false, err
+		}
+		if i == len(parts)-1 {
+			break
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return gitignore.ShouldIgnore(filepath.Join(p.roots[root], relPath)), nil
+}
+
+// newJob applies the include/exclude filters, the extension lookup and the size
+// limit to the file relPath below the given root. It returns the reason if the file is skipped.
+func (p *packer) newJob(root int, relPath string, info os.FileInfo) (packJob, string) {
+	absdir := p.roots[root]
+
+	// Exclude wins over include
+	if matchesFilter(p.opts.Excludes, relPath) {
+		return packJob{}, "excluded"
+	}
+	if len(p.opts.Includes) > 0 && !matchesFilter(p.opts.Includes, relPath) {
+		return packJob{}, "not included"
+	}
+
+	ext := filepath.Ext(relPath)
+	commentStyle, ok := p.opts.CommentStyles[ext]
+	if !ok && p.opts.DetectShebang {
+		var skip string
+		ext, skip = p.scriptExt(filepath.Join(absdir, relPath), info)
+		if skip != "" {
+			return packJob{}, skip
+		}
+		commentStyle, ok = p.opts.CommentStyles[ext]
+	}
+	if !ok {
+		return packJob{}, "not a code file"
+	}
+
+	// Check the size before reading so huge files are never loaded
+	if p.opts.MaxFileSize > 0 && info.Size() > p.opts.MaxFileSize {
+		return packJob{}, fmt.Sprintf("file too large, %d bytes", info.Size())
+	}
+
+	return packJob{
+		path:         filepath.Join(absdir, relPath),
+		header:       headerPath(p.opts.PathStyle, absdir, p.rootNames[root], relPath),
+		ext:          ext,
+		commentStyle: commentStyle,
+	}, ""
+}
+
+// shebangMaxSize is the largest file without the executable bit that is
+// checked for a shebang line, so big data files aren't opened during the walk
+const shebangMaxSize = 64 * 1024
+
+// scriptExt reads the shebang line of a file without a known extension and
+// returns the extension of the script's language, or the reason the file is skipped
+func (p *packer) scriptExt(path string, info os.FileInfo) (string, string) {
+	if info.Mode()&0o111 == 0 && info.Size() > shebangMaxSize {
+		return "", "not a code file"
+	}
+	line, err := readFirstLine(path)
+	if err != nil {
+		p.logf("Error looking for a shebang: %v\n", err)
+		return "", "not a code file"
+	}
+
+	ext, ok := detectByShebang(line)
+	if !ok {
+		if interpreter := shebangInterpreter(line); interpreter != "" {
+			return "", fmt.Sprintf("not a code file, unknown interpreter %s", interpreter)
+		}
+		return "", "not a code file"
+	}
+	return ext, ""
+}
+
+// process reads a single file and renders its block. It runs in the worker pool.
+func (p *packer) process(job packJob) packResult {
+	res := packResult{index: job.index, path: job.path, header: job.header, ext: job.ext, commentStyle: job.commentStyle}
+
+	code, err := readCodeFile(job.path)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	if len(code) == 0 {
+		res.skip = "empty file"
+		return res
+	}
+	if p.opts.Transcode != "" {
+		code = transcode(code, p.opts.Transcode)
+	}
+	if !p.opts.AllowBinary {
+		if problem := textProblem(code); problem != "" {
+			res.skip = problem
+			return res
+		}
+	}
+
+	res.size = len(code)
+	if p.opts.Dedupe {
+		res.hash = sha256.Sum256(code)
+	}
+
+	// These transforms only change the emitted block, not the size or hash of the file
+	if p.opts.StripComments {
+		code = stripComments(code, job.commentStyle, job.ext)
+	}
+	if p.opts.StripBlank {
+		code = collapseBlankLines(code)
+	}
+	if (p.opts.StripComments || p.opts.StripBlank) && len(bytes.TrimSpace(code)) == 0 {
+		res.skip = "empty after stripping"
+		return res
+	}
+
+	if !p.opts.DryRun {
+		if p.opts.LineNumbers {
+			code = numberLines(code)
+		}
+		if p.opts.Format == FormatJSON {
+			res.block, res.err = formatJSONEntry(job.path, jsonFile{
+				Path:     filepath.ToSlash(job.header),
+				Language: languageFor(job.ext),
+				Bytes:    res.size,
+				Content:  string(code),
+			})
+		} else {
+			res.block = formatBlock(p.opts.Format, job, code)
+		}
+	}
+	return res
+}
+
+// dedupeRoots drops input directories that are equal to or nested inside another
+// input directory, so that no file is walked and emitted twice
+func (p *packer) dedupeRoots(roots []string) []string {
+	unique := make([]string, 0, len(roots))
+	for i, root := range roots {
+		overlap := ""
+		for j, other := range roots {
+			if i == j {
+				continue
+			}
+			rel, err := filepath.Rel(other, root)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			// Identical roots keep their first occurrence, nested roots are dropped
+			if rel != "." || j < i {
+				overlap = other
+				break
+			}
+		}
+
+		if overlap != "" {
+			p.logf("Skipping input directory %s (overlaps %s)\n", root, overlap)
+			continue
+		}
+		unique = append(unique, root)
+	}
+	return unique
+}
@@ -0,0 +1,214 @@
+package codepacker
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// headers returns the paths of the "// path" file headers in text output
+func headers(out string) []string {
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		if path, ok := strings.CutPrefix(line, "// "); ok {
+			paths = append(paths, filepath.ToSlash(strings.TrimSpace(path)))
+		}
+	}
+	return paths
+}
+
+func TestPackSortsByPath(t *testing.T) {
+	dir := newRepo(t, nil)
+	// Create the files out of order, with modification times in reverse order too
+	names := []string{"zeta.go", "sub/b.go", "alpha.go", "sub/a.go", "Beta.go", "m/z.go"}
+	for i, name := range names {
+		writeTree(t, dir, map[string]string{name: "package x\n"})
+		mtime := time.Now().Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, filepath.FromSlash(name)), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, jobs := range []int{1, 8} {
+		var out bytes.Buffer
+		if _, err := Pack(Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative, Jobs: jobs}, &out); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Beta.go", "alpha.go", "m/z.go", "sub/a.go", "sub/b.go", "zeta.go"}
+		if got := headers(out.String()); !slices.Equal(got, want) {
+			t.Errorf("jobs=%d: headers %q, want %q", jobs, got, want)
+		}
+	}
+}
+
+// goldenTree is the input directory of the golden output tests
+var goldenTree = map[string]string{
+	"main.go":         "package main\n\nfunc main() {}\n",
+	"lib/util.py":     "def util():\n    return 1\n",
+	"web/style.css":   "body { margin: 0; }",
+	"notes.txt":       "not a code file\n",
+	"empty.go":        "",
+	".gitignore":      "secret.go\n",
+	"secret.go":       "package secret\n",
+	"node_modules/":   "",
+	"docs/index.html": "<p>hi</p>\n",
+}
+
+// goldenOutput is the text output of Pack for goldenTree below a root named "project"
+const goldenOutput = `Project Structure:
+├── .gitignore
+├── docs
+│   └── index.html
+├── empty.go
+├── lib
+│   └── util.py
+├── main.go
+├── notes.txt
+└── web
+    └── style.css
+
+
+<!-- project/docs/index.html -->
+<p>hi</p>
+
+
+# project/lib/util.py 
+def util():
+    return 1
+
+
+// project/main.go 
+package main
+
+func main() {}
+
+
+/* project/web/style.css */
+body { margin: 0; }
+
+`
+
+func TestPackGolden(t *testing.T) {
+	project := filepath.Join(newRepo(t, nil), "project")
+	writeTree(t, project, goldenTree)
+
+	var out bytes.Buffer
+	stats, err := Pack(Options{InputDirs: []string{project}}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != goldenOutput {
+		t.Errorf("Pack() output =\n%s\nwant\n%s", got, goldenOutput)
+	}
+	if want := (Stats{Files: 4, Bytes: 83}); stats != want {
+		t.Errorf("Pack() stats = %+v, want %+v", stats, want)
+	}
+}
+
+// nopCloser buffers a part written by PackSplit
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestPackSplitMatchesPack(t *testing.T) {
+	dir := newRepo(t, goldenTree)
+	opts := Options{InputDirs: []string{dir}}
+
+	var whole bytes.Buffer
+	if _, err := Pack(opts, &whole); err != nil {
+		t.Fatal(err)
+	}
+
+	var parts []*bytes.Buffer
+	create := func(part int) (io.WriteCloser, error) {
+		if part != len(parts)+1 {
+			t.Errorf("create(%d) called after %d parts", part, len(parts))
+		}
+		parts = append(parts, new(bytes.Buffer))
+		return nopCloser{parts[len(parts)-1]}, nil
+	}
+	stats, err := PackSplit(opts, 80, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var joined bytes.Buffer
+	for _, part := range parts {
+		joined.Write(part.Bytes())
+	}
+	if joined.String() != whole.String() {
+		t.Errorf("concatenated parts =\n%s\nwant\n%s", joined.String(), whole.String())
+	}
+	if stats.Parts != len(parts) || stats.Parts < 2 {
+		t.Errorf("stats.Parts = %d, want %d parts and at least 2", stats.Parts, len(parts))
+	}
+}
+
+func TestPackDedupe(t *testing.T) {
+	const content = "package util\n\nfunc Helper() {}\n"
+	dir := newRepo(t, map[string]string{
+		"src/util/helper.go": content,
+		"legacy/helper.go":   content,
+		"src/util/other.go":  "package util\n",
+	})
+
+	tests := []struct {
+		format string
+		want   string // Output of the duplicate, which sorts before the original
+	}{
+		{FormatText, "// legacy/helper.go \npackage util\n"},
+		{FormatText, "// src/util/helper.go (duplicate of legacy/helper.go) \n\n\n"},
+		{FormatMarkdown, "### src/util/helper.go\n\nDuplicate of `legacy/helper.go`.\n\n\n"},
+		{FormatJSON, `{"path":"src/util/helper.go","language":"go","bytes":31,"duplicate_of":"legacy/helper.go"}`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		stats, err := Pack(Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative, Format: tt.format, Dedupe: true}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%s output doesn't contain %q:\n%s", tt.format, tt.want, out.String())
+		}
+		if n := strings.Count(out.String(), "func Helper"); n != 1 {
+			t.Errorf("%s output contains the duplicated content %d times, want once", tt.format, n)
+		}
+		if want := (Stats{Files: 3, Bytes: len(content) + len("package util\n")}); stats != want {
+			t.Errorf("%s stats = %+v, want %+v", tt.format, stats, want)
+		}
+	}
+}
+
+func TestPackSkipsFilesEmptyAfterStripping(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"doc.go":  "// Package x is documented elsewhere\n// and has no code of its own\n",
+		"main.go": "package x\n",
+	})
+
+	tests := []struct {
+		format string
+		header string // Header doc.go would have been written with
+	}{
+		{FormatText, "// doc.go "},
+		{FormatMarkdown, "### doc.go"},
+		{FormatJSON, `"path":"doc.go"`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		stats, err := Pack(Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative, Format: tt.format, StripComments: true}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Files != 1 {
+			t.Errorf("%s: packed %d files, want 1", tt.format, stats.Files)
+		}
+		if strings.Contains(out.String(), tt.header) {
+			t.Errorf("%s output contains %q:\n%s", tt.format, tt.header, out.String())
+		}
+	}
+}
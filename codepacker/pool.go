@@ -0,0 +1,122 @@
+package codepacker
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// packJob is a file queued for reading by the worker pool
+type packJob struct {
+	index        int          // Position of the file in walk order
+	path         string       // Absolute path of the file
+	header       string       // Path shown in the file's header
+	ext          string       // File extension, used for the markdown language tag
+	commentStyle CommentStyle // Comment markers for the text header
+}
+
+// packResult is the outcome of processing a packJob
+type packResult struct {
+	index        int
+	path         string
+	header       string
+	ext          string
+	commentStyle CommentStyle
+	size         int      // Size of the file contents in bytes
+	hash         [32]byte // SHA-256 of the contents, only set when deduplicating
+	block        []byte   // Formatted header and contents, nil if skipped
+	skip         string   // Reason the file was skipped, if any
+	err          error
+}
+
+// sortJobs orders jobs by their emitted path so the output is identical across
+// runs and platforms. Paths are compared with "/" separators, and jobs with the
+// same path keep the order of their input directories.
+func sortJobs(jobs []packJob) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return filepath.ToSlash(jobs[i].header) < filepath.ToSlash(jobs[j].header)
+	})
+}
+
+// errPoolAborted stops the walk after an earlier error
+var errPoolAborted = errors.New("aborted")
+
+// runPool runs produce to submit jobs, processes them concurrently with the given
+// number of workers and passes the results to emit in submission order.
+// At most workers*4 jobs are in flight at once, so memory use stays bounded
+// no matter how many files are packed. The first error from produce, a worker
+// or emit aborts the run and is returned.
+func runPool(workers int, produce func(submit func(packJob) error) error, process func(packJob) packResult, emit func(packResult) error) error {
+	slots := make(chan struct{}, workers*4)
+	jobs := make(chan packJob)
+	results := make(chan packResult, workers*4)
+	abort := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- process(job)
+			}
+		}()
+	}
+
+	produceErr := make(chan error, 1)
+	go func() {
+		next := 0
+		err := produce(func(job packJob) error {
+			// Wait for a free slot so only a bounded number of blocks is buffered
+			select {
+			case slots <- struct{}{}:
+			case <-abort:
+				return errPoolAborted
+			}
+			job.index = next
+			next++
+			jobs <- job
+			return nil
+		})
+		close(jobs)
+		wg.Wait()
+		close(results)
+		produceErr <- err
+	}()
+
+	// Write results in submission order, holding back those that finish early
+	var firstErr error
+	pending := make(map[int]packResult)
+	next := 0
+	for res := range results {
+		if firstErr != nil {
+			continue
+		}
+		pending[res.index] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-slots
+
+			err := r.err
+			if err == nil {
+				err = emit(r)
+			}
+			if err != nil {
+				firstErr = err
+				close(abort)
+				break
+			}
+		}
+	}
+
+	if err := <-produceErr; firstErr == nil && err != nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,138 @@
+package codepacker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// binarySniffLen is how much of a file isBinary inspects
+const binarySniffLen = 8 * 1024
+
+// isBinary reports whether data looks like binary rather than text. Data is
+// considered binary if the first binarySniffLen bytes contain a NUL byte or
+// more than 30% control characters that don't occur in text files.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	if len(data) == 0 {
+		return false
+	}
+
+	nonPrintable := 0
+	for _, c := range data {
+		switch {
+		case c == 0:
+			return true
+		case c == '\t', c == '\n', c == '\r', c == '\f', c == '\b', c == 0x1b:
+			// Whitespace, backspace and ANSI escapes are common in text
+		case c < 0x20, c == 0x7f:
+			nonPrintable++
+		}
+	}
+
+	return nonPrintable*100 > len(data)*30
+}
+
+// Byte order marks of UTF-16 text
+var (
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// textProblem reports why data can't be packed as UTF-8 text, or "" if it can.
+// UTF-16 text is recognized by its byte order mark and reported as such rather
+// than as binary, even though it usually contains NUL bytes.
+func textProblem(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, utf16LEBOM), bytes.HasPrefix(data, utf16BEBOM):
+		return "UTF-16 text"
+	case isBinary(data):
+		return "binary file"
+	case !utf8.Valid(data):
+		return "invalid UTF-8"
+	}
+	return ""
+}
+
+// transcode converts data to UTF-8. Data starting with a UTF-16 byte order mark
+// is always decoded as UTF-16, and with TranscodeLatin1 any other data that
+// isn't valid UTF-8 is decoded as Latin-1. Valid UTF-8 is returned unchanged.
+func transcode(data []byte, encoding string) []byte {
+	switch {
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[2:], true)
+	case encoding == TranscodeLatin1 && !utf8.Valid(data):
+		// Latin-1 bytes are the first 256 Unicode code points
+		out := make([]byte, 0, len(data)+len(data)/4)
+		for _, c := range data {
+			out = utf8.AppendRune(out, rune(c))
+		}
+		return out
+	}
+	return data
+}
+
+// decodeUTF16 converts UTF-16 data without its byte order mark to UTF-8.
+// A trailing odd byte and unpaired surrogates become U+FFFD.
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+
+	out := make([]byte, 0, len(data))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	if len(data)%2 != 0 {
+		out = utf8.AppendRune(out, utf8.RuneError)
+	}
+	return out
+}
+
+// shebangSniffLen is how much of a file readFirstLine reads at most
+const shebangSniffLen = 512
+
+// readFirstLine returns the first line of a file, without its line ending
+func readFirstLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, shebangSniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+	line, _, _ := bytes.Cut(buf[:n], []byte("\n"))
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+func readCodeFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	return b, nil
+}
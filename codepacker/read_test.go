@@ -0,0 +1,108 @@
+package codepacker
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16LE encodes s as UTF-16LE with a byte order mark
+func utf16LE(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func TestIsBinary(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d, 'I', 'H', 'D', 'R'}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"ASCII source", []byte("package main\n\nfunc main() {}\n"), false},
+		{"UTF-8 text", []byte("// Grüße, 世界 🚀\nconst s = \"héllo\"\n"), false},
+		{"ANSI escapes and tabs", []byte("\x1b[31mred\x1b[0m\tdone\r\n"), false},
+		{"UTF-16 with NULs", utf16LE("x = 1\n"), true},
+		{"PNG header", png, true},
+		{"mostly control characters", []byte("\x01\x02\x03\x04ab"), true},
+		{"NUL after sniff length", []byte(strings.Repeat("a", binarySniffLen) + "\x00"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBinary(tt.data); got != tt.want {
+				t.Errorf("isBinary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"valid UTF-8", []byte("s := \"Grüße\"\n"), ""},
+		{"UTF-16LE with BOM", utf16LE("x = 1\n"), "UTF-16 text"},
+		{"UTF-16BE with BOM", []byte{0xfe, 0xff, 0, 'x', 0, '\n'}, "UTF-16 text"},
+		{"invalid byte sequence", []byte("caf\xe9 au lait\n"), "invalid UTF-8"},
+		{"binary", []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0x0d}, "binary file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textProblem(tt.data); got != tt.want {
+				t.Errorf("textProblem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		want     string
+	}{
+		{"valid UTF-8 unchanged", []byte("Grüße\n"), TranscodeLatin1, "Grüße\n"},
+		{"UTF-16LE with BOM", utf16LE("Grüße 🚀\n"), TranscodeUTF16, "Grüße 🚀\n"},
+		{"UTF-16BE with BOM", []byte{0xfe, 0xff, 0, 'h', 0, 'i'}, TranscodeUTF16, "hi"},
+		{"UTF-16 BOM wins over latin1", utf16LE("x\n"), TranscodeLatin1, "x\n"},
+		{"latin1", []byte("caf\xe9\n"), TranscodeLatin1, "café\n"},
+		{"invalid UTF-8 without latin1", []byte("caf\xe9\n"), TranscodeUTF16, "caf\xe9\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(transcode(tt.data, tt.encoding)); got != tt.want {
+				t.Errorf("transcode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		bigEndian bool
+		want      string
+	}{
+		{"little endian", []byte{'o', 0, 'k', 0}, false, "ok"},
+		{"big endian", []byte{0, 'o', 0, 'k'}, true, "ok"},
+		{"surrogate pair", []byte{0x3d, 0xd8, 0x80, 0xde}, false, "🚀"},
+		{"odd trailing byte", []byte{'o', 0, 'k', 0, 'x'}, false, "ok�"},
+		{"unpaired surrogate", []byte{0x3d, 0xd8, 'a', 0}, false, "�a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(decodeUTF16(tt.data, tt.bigEndian)); got != tt.want {
+				t.Errorf("decodeUTF16() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package codepacker
+
+import (
+	"bytes"
+	"strings"
+)
+
+// stringDelims are the opening and closing delimiters of a string literal that can span lines
+type stringDelims struct {
+	open, close string
+}
+
+// multilineStrings lists the string literals that can span lines, by extension.
+// Comment stripping never touches a line that starts inside one of them, so a
+// "#" line in a Python docstring survives. Languages not listed here are only
+// protected against markers inside single-line strings.
+var multilineStrings = map[string][]stringDelims{
+	".go":     {{"`", "`"}},
+	".js":     {{"`", "`"}},
+	".jsx":    {{"`", "`"}},
+	".ts":     {{"`", "`"}},
+	".tsx":    {{"`", "`"}},
+	".py":     {{`"""`, `"""`}, {"'''", "'''"}},
+	".dart":   {{`"""`, `"""`}, {"'''", "'''"}},
+	".groovy": {{`"""`, `"""`}, {"'''", "'''"}},
+	".java":   {{`"""`, `"""`}},
+	".kt":     {{`"""`, `"""`}},
+	".scala":  {{`"""`, `"""`}},
+	".swift":  {{`"""`, `"""`}},
+	".lua":    {{"[[", "]]"}}, // Also covers --[[ block comments
+}
+
+// stripComments removes full-line comments from code, i.e. lines whose first
+// non-whitespace content is the prepend marker of style. For styles with an
+// append marker, the comment also has to close at the end of the line. Lines
+// that start inside, open or close a multi-line string of the language are
+// kept, as is a leading shebang line.
+func stripComments(code []byte, style CommentStyle, ext string) []byte {
+	delims := multilineStrings[ext]
+	open := -1 // Index of the multi-line string the current line starts in
+
+	var buf bytes.Buffer
+	buf.Grow(len(code))
+	for i, line := range bytes.SplitAfter(code, []byte("\n")) {
+		text := strings.TrimSpace(string(line))
+		next := scanStrings(text, delims, open)
+		isShebang := i == 0 && strings.HasPrefix(text, "#!")
+		if open < 0 && next < 0 && !isShebang && isCommentLine(text, style) {
+			continue
+		}
+		open = next
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
+
+// isCommentLine reports whether the trimmed line consists of a single comment in style
+func isCommentLine(text string, style CommentStyle) bool {
+	if style.Prepend == "" || !strings.HasPrefix(text, style.Prepend) {
+		return false
+	}
+	if style.Append == "" {
+		return true
+	}
+	rest := text[len(style.Prepend):]
+	end := strings.Index(rest, style.Append)
+	return end >= 0 && end == len(rest)-len(style.Append)
+}
+
+// scanStrings returns the index of the multi-line string in delims that line
+// ends inside of, given the one it starts inside of, or -1 for none
+func scanStrings(line string, delims []stringDelims, open int) int {
+	for {
+		if open >= 0 {
+			end := strings.Index(line, delims[open].close)
+			if end < 0 {
+				return open
+			}
+			line = line[end+len(delims[open].close):]
+			open = -1
+			continue
+		}
+
+		// Find the delimiter that opens first
+		at := len(line)
+		for i, d := range delims {
+			if start := strings.Index(line, d.open); start >= 0 && start < at {
+				open, at = i, start
+			}
+		}
+		if open < 0 {
+			return -1
+		}
+		line = line[at+len(delims[open].open):]
+	}
+}
+
+// collapseBlankLines replaces every run of consecutive blank lines in code,
+// including lines of only whitespace, with a single empty line
+func collapseBlankLines(code []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(code))
+	blank := false
+	for _, line := range bytes.SplitAfter(code, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if blank {
+				continue
+			}
+			blank = true
+			// Keep only the line ending, including the \r of CRLF files
+			line = bytes.TrimLeft(line, " \t\v\f")
+		} else {
+			blank = false
+		}
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
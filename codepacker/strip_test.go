@@ -0,0 +1,68 @@
+package codepacker
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		code string
+		want string
+	}{
+		{
+			"go line comments",
+			".go",
+			"// Package x does things\npackage x\n\n\t// indented comment\nfunc f() {} // trailing comment stays\n",
+			"package x\n\nfunc f() {} // trailing comment stays\n",
+		},
+		{
+			"python hash comments",
+			".py",
+			"#!/usr/bin/env python3\n# comment\nimport os\n    # indented\nprint(os.sep)\n",
+			"#!/usr/bin/env python3\nimport os\nprint(os.sep)\n",
+		},
+		{
+			"comment marker inside a string",
+			".go",
+			"s := \"// not a comment\"\n// a comment\n",
+			"s := \"// not a comment\"\n",
+		},
+		{
+			"go raw string spanning lines",
+			".go",
+			"s := `\n// kept, inside the string\n`\n// dropped\n",
+			"s := `\n// kept, inside the string\n`\n",
+		},
+		{
+			"python docstring",
+			".py",
+			"\"\"\"\n# kept, inside the docstring\n\"\"\"\n# dropped\n",
+			"\"\"\"\n# kept, inside the docstring\n\"\"\"\n",
+		},
+		{
+			"crlf line endings",
+			".go",
+			"// dropped\r\npackage x\r\n",
+			"package x\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripComments([]byte(tt.code), FileExtToComment[tt.ext], tt.ext)
+			if !bytes.Equal(got, []byte(tt.want)) {
+				t.Errorf("stripComments(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapseBlankLines(t *testing.T) {
+	code := "a\n\n  \n\t\nb\r\n \r\n\r\nc\n\n"
+	want := "a\n\nb\r\n\r\nc\n\n"
+	if got := collapseBlankLines([]byte(code)); !bytes.Equal(got, []byte(want)) {
+		t.Errorf("collapseBlankLines(%q) = %q, want %q", code, got, want)
+	}
+}
@@ -0,0 +1,221 @@
+package codepacker
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TreeNode represents a node in the file tree
+type TreeNode struct {
+	name     string
+	isDir    bool
+	children map[string]*TreeNode
+}
+
+// NewTreeNode creates a new tree node
+func NewTreeNode(name string, isDir bool) *TreeNode {
+	return &TreeNode{
+		name:     name,
+		isDir:    isDir,
+		children: make(map[string]*TreeNode),
+	}
+}
+
+// GenerateTreeView creates a tree view of the directory structure.
+// When several roots are given, each one becomes a top-level node named after its directory.
+func GenerateTreeView(roots ...string) (string, error) {
+	gitignores := make([]*GitIgnore, len(roots))
+	for i, root := range roots {
+		// Continue without gitignore if there's an error, but don't return the error
+		gitignore, err := LoadGitIgnore(root)
+		if err != nil {
+			gitignore = &GitIgnore{patterns: []ignorePattern{}, baseDir: root}
+		}
+		gitignores[i] = gitignore
+	}
+	return generateTreeView(roots, gitignores, false)
+}
+
+// generateTreeView creates the tree view of roots, filtering each root with its own ignore rules.
+// With followSymlinks set, the contents of symlinked directories are shown too.
+func generateTreeView(roots []string, gitignores []*GitIgnore, followSymlinks bool) (string, error) {
+	tree := NewTreeNode("", true)
+	names := rootNames(roots)
+
+	for i, root := range roots {
+		node := tree
+		if len(roots) > 1 {
+			node = addTreePath(tree, names[i], true)
+		}
+
+		if err := addTreeNodes(node, root, gitignores[i], followSymlinks); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Project Structure:\n")
+	printTree(tree, "", true, &sb)
+	return sb.String(), nil
+}
+
+// addTreeNodes walks root and adds every non-ignored entry below tree
+func addTreeNodes(tree *TreeNode, root string, gitignore *GitIgnore, followSymlinks bool) error {
+	return walk(root, followSymlinks, nil, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Check if path should be ignored based on gitignore rules
+		if gitignore != nil && gitignore.ShouldIgnore(path) {
+			if info.IsDir() && !gitignore.CanReinclude(path) {
+				return filepath.SkipDir
+			}
+			if info.IsDir() {
+				return gitignore.EnterDir(path)
+			}
+			return nil
+		}
+		if gitignore != nil && info.IsDir() {
+			// A .gitignore in the directory applies to everything below it
+			if err := gitignore.EnterDir(path); err != nil {
+				return err
+			}
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		// Skip root directory
+		if relPath == "." {
+			return nil
+		}
+
+		addTreePath(tree, relPath, info.IsDir())
+		return nil
+	})
+}
+
+// addTreePath adds relPath below tree, creating its parent directories as needed,
+// and returns its node
+func addTreePath(tree *TreeNode, relPath string, isDir bool) *TreeNode {
+	parts := strings.Split(relPath, string(filepath.Separator))
+	current := tree
+
+	// Build tree structure
+	for i, part := range parts {
+		isLast := i == len(parts)-1
+		if _, exists := current.children[part]; !exists {
+			current.children[part] = NewTreeNode(part, !isLast || isDir)
+		}
+		current = current.children[part]
+	}
+	return current
+}
+
+// rootNames returns the names the files of each root are shown under with
+// several roots, which is the base name of the root. Roots sharing a base name
+// are named by their path relative to the closest directory holding all of
+// them instead, so -indir a/src,b/src shows a/src and b/src rather than src twice.
+func rootNames(roots []string) []string {
+	names := make([]string, len(roots))
+	count := make(map[string]int)
+	for i, root := range roots {
+		names[i] = filepath.Base(root)
+		count[names[i]]++
+	}
+
+	var clashing []string
+	for i, root := range roots {
+		if count[names[i]] > 1 {
+			clashing = append(clashing, root)
+		}
+	}
+	if len(clashing) == 0 {
+		return names
+	}
+
+	ancestor := filepath.Dir(clashing[0])
+	for _, root := range clashing {
+		for {
+			if _, _, ok := relativeTo(ancestor, root); ok {
+				break
+			}
+			parent := filepath.Dir(ancestor)
+			if parent == ancestor {
+				break
+			}
+			ancestor = parent
+		}
+	}
+
+	for i, root := range roots {
+		if count[names[i]] > 1 {
+			if rel, _, ok := relativeTo(ancestor, root); ok {
+				names[i] = rel
+			}
+		}
+	}
+	return names
+}
+
+// generateFileTreeView creates a tree view that only contains the given files.
+// Every file has to be inside one of roots, which are laid out like in generateTreeView.
+func generateFileTreeView(roots []string, files []string) string {
+	tree := NewTreeNode("", true)
+	names := rootNames(roots)
+
+	for _, file := range files {
+		for i, root := range roots {
+			relPath, _, ok := relativeTo(root, file)
+			if !ok {
+				continue
+			}
+
+			node := tree
+			if len(roots) > 1 {
+				node = addTreePath(tree, names[i], true)
+			}
+			addTreePath(node, relPath, false)
+			break
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Project Structure:\n")
+	printTree(tree, "", true, &sb)
+	return sb.String()
+}
+
+// printTree recursively prints the tree structure
+func printTree(node *TreeNode, prefix string, isLast bool, sb *strings.Builder) {
+	if node.name != "" {
+		sb.WriteString(prefix)
+		if isLast {
+			sb.WriteString("└── ")
+			prefix += "    "
+		} else {
+			sb.WriteString("├── ")
+			prefix += "│   "
+		}
+		sb.WriteString(node.name)
+		sb.WriteString("\n")
+	}
+
+	// Sort children for consistent output
+	children := make([]string, 0, len(node.children))
+	for name := range node.children {
+		children = append(children, name)
+	}
+	sort.Strings(children)
+
+	for i, name := range children {
+		child := node.children[name]
+		isLast := i == len(children)-1
+		printTree(child, prefix, isLast, sb)
+	}
+}
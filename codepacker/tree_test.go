@@ -0,0 +1,55 @@
+package codepacker
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRootNames(t *testing.T) {
+	root := filepath.FromSlash("/work")
+	join := func(p string) string { return filepath.Join(root, filepath.FromSlash(p)) }
+
+	tests := []struct {
+		name  string
+		roots []string
+		want  []string
+	}{
+		{"unique base names", []string{join("backend"), join("web/frontend")}, []string{"backend", "frontend"}},
+		{"clashing base names", []string{join("a/src"), join("b/src"), join("lib")}, []string{"a/src", "b/src", "lib"}},
+		{"clashing at different depths", []string{join("x/y/src"), join("z/src")}, []string{"x/y/src", "z/src"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rootNames(tt.roots)
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rootNames(%q) = %q, want %q", tt.roots, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTreeViewClashingRoots(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"a/src/x.go": "package a\n",
+		"b/src/x.go": "package b\n",
+	})
+	got, err := generateTreeView([]string{filepath.Join(dir, "a", "src"), filepath.Join(dir, "b", "src")}, []*GitIgnore{nil, nil}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Project Structure:\n" +
+		"├── a\n" +
+		"│   └── src\n" +
+		"│       └── x.go\n" +
+		"└── b\n" +
+		"    └── src\n" +
+		"        └── x.go\n"
+	if got != want {
+		t.Errorf("generateTreeView() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package codepacker
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// walk calls fn for every file and directory below root like filepath.Walk.
+// With follow set, symlinked directories are descended into and symlinked files
+// are reported with the info of their target, while fn still sees the path
+// through the link. Links are never followed to a directory inside root, which
+// the walk reaches on its own, and directories that were already walked or that
+// contain root are skipped too. Skipped links are reported to onRevisit, which
+// may be nil, with inRoot set and target below root if the target is inside
+// root. This way every directory is walked once, and a link back to one of its
+// parent directories can't make the walk loop forever.
+func walk(root string, follow bool, onRevisit func(path, target string, inRoot bool), fn filepath.WalkFunc) error {
+	if !follow {
+		return filepath.Walk(root, fn)
+	}
+
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	w := &linkWalker{root: root, realRoot: real, visited: make(map[string]bool), onRevisit: onRevisit, fn: fn}
+	return w.walk(root, real, true)
+}
+
+// linkWalker holds the state of a walk that follows symlinks
+type linkWalker struct {
+	root      string          // Walked root as given
+	realRoot  string          // Real path of root
+	visited   map[string]bool // Real paths of the directories walked so far
+	onRevisit func(path, target string, inRoot bool)
+	fn        filepath.WalkFunc
+}
+
+// walk walks the real directory real, reporting its entries below the path logical.
+// The directory itself is only reported if reportRoot is set, a linked directory
+// has already been reported through its link.
+func (w *linkWalker) walk(logical, real string, reportRoot bool) error {
+	return filepath.Walk(real, func(path string, info os.FileInfo, err error) error {
+		rel, relErr := filepath.Rel(real, path)
+		if relErr != nil {
+			return relErr
+		}
+		linkPath := filepath.Join(logical, rel)
+		if err != nil {
+			return w.fn(linkPath, info, err)
+		}
+
+		if info.IsDir() {
+			// real is fully resolved, so everything below it is a real path too.
+			// A linked directory may hold a directory walked through another link.
+			if w.visited[path] {
+				w.revisit(linkPath, path, false)
+				return filepath.SkipDir
+			}
+			w.visited[path] = true
+			if path == real && !reportRoot {
+				return nil
+			}
+			return w.fn(linkPath, info, nil)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return w.fn(linkPath, info, nil)
+		}
+
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			// Report broken links as they are, the callback skips them
+			return w.fn(linkPath, info, nil)
+		}
+		targetInfo, err := os.Stat(target)
+		if err != nil {
+			return w.fn(linkPath, info, err)
+		}
+		targetInfo = linkInfo{targetInfo, info.Name()}
+
+		if !targetInfo.IsDir() {
+			return w.fn(linkPath, targetInfo, nil)
+		}
+		// Directories inside the root are walked without the link, maybe later
+		// on, so only directories outside of it are walked through links
+		if rel, _, inRoot := relativeTo(w.realRoot, target); inRoot {
+			w.revisit(linkPath, filepath.Join(w.root, rel), true)
+			return nil
+		}
+		// A directory containing the root would walk the root once more
+		if _, _, containsRoot := relativeTo(target, w.realRoot); containsRoot || w.visited[target] {
+			w.revisit(linkPath, target, false)
+			return nil
+		}
+
+		// Let the callback skip the linked directory before descending into it.
+		// SkipDir must not be returned for the link itself, since filepath.Walk
+		// would then skip the rest of the directory holding it.
+		if err := w.fn(linkPath, targetInfo, nil); err != nil {
+			if err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+		return w.walk(linkPath, target, false)
+	})
+}
+
+// revisit reports a link that isn't followed to onRevisit if it is set
+func (w *linkWalker) revisit(path, target string, inRoot bool) {
+	if w.onRevisit != nil {
+		w.onRevisit(path, target, inRoot)
+	}
+}
+
+// linkInfo is the info of a symlink target under the name of the link
+type linkInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i linkInfo) Name() string {
+	return i.name
+}
@@ -0,0 +1,111 @@
+package codepacker
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// symlinkTree creates a project inside a repository with links back to a
+// parent, to a sibling walked later, to the repository holding the project,
+// twice to the same directory outside of it, to a directory below that one
+// and to a file. It returns the project directory.
+func symlinkTree(t *testing.T) string {
+	t.Helper()
+	outside := t.TempDir()
+	writeTree(t, outside, map[string]string{
+		"ext/e.go":     "package ext\n",
+		"ext/sub/s.go": "package sub\n",
+	})
+	repo := newRepo(t, map[string]string{
+		"project/a/a.go": "package a\n",
+		"project/b/b.go": "package b\n",
+	})
+	dir := filepath.Join(repo, "project")
+
+	links := []struct{ target, link string }{
+		{"..", "a/loop"},
+		{"../b", "a/tob"},
+		{filepath.Join(outside, "ext", "sub"), "bsub"},
+		{filepath.Join(outside, "ext"), "c"},
+		{filepath.Join(outside, "ext"), "d"},
+		{filepath.Join("a", "a.go"), "link.go"},
+		{repo, "up"},
+	}
+	for _, l := range links {
+		if err := os.Symlink(l.target, filepath.Join(dir, filepath.FromSlash(l.link))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestWalkFollowSymlinks(t *testing.T) {
+	dir := symlinkTree(t)
+
+	var files, revisited []string
+	onRevisit := func(path, target string, inRoot bool) {
+		rel, _ := filepath.Rel(dir, path)
+		if inRoot {
+			rel += " (in root)"
+		}
+		revisited = append(revisited, filepath.ToSlash(rel))
+	}
+	err := walk(dir, true, onRevisit, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a/a.go", "b/b.go", "bsub/s.go", "c/e.go", "link.go"}; !slices.Equal(files, want) {
+		t.Errorf("walked files %q, want %q", files, want)
+	}
+	if want := []string{"a/loop (in root)", "a/tob (in root)", "c/sub", "d", "up"}; !slices.Equal(revisited, want) {
+		t.Errorf("skipped links %q, want %q", revisited, want)
+	}
+}
+
+func TestPackSkipsSymlinksByDefault(t *testing.T) {
+	dir := symlinkTree(t)
+
+	var out bytes.Buffer
+	if _, err := Pack(Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := headers(out.String()), []string{"a/a.go", "b/b.go"}; !slices.Equal(got, want) {
+		t.Errorf("packed %q, want %q", got, want)
+	}
+}
+
+func TestPackSymlinkToIgnoredDirectory(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"main.go":          "package main\n",
+		"build/gen/gen.go": "package gen\n",
+	})
+	if err := os.Symlink(filepath.Join("build", "gen"), filepath.Join(dir, "gen")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	var out, log bytes.Buffer
+	opts := Options{InputDirs: []string{dir}, PathStyle: PathStyleRelative, FollowSymlinks: true, Log: &log}
+	if _, err := Pack(opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := headers(out.String()), []string{"main.go"}; !slices.Equal(got, want) {
+		t.Errorf("packed %q, want %q", got, want)
+	}
+	if !strings.Contains(log.String(), "symlink to ignored directory") {
+		t.Errorf("log doesn't report the link to an ignored directory:\n%s", log.String())
+	}
+}
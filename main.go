@@ -2,13 +2,19 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
-	"sort"
+	"runtime"
+	"strconv"
 	"strings"
+
+	"github.com/helshabini/codepacker/codepacker"
 )
 
 const helpText = `Code Packer - concatenates source code files with appropriate comment markers
@@ -18,507 +24,631 @@ Usage:
 
 Flags:
   -indir string
-        Input directory to process (default ".")
+        Input directory to process (default "."). May be repeated or given
+        as a comma-separated list to pack several directories together
   -outfile string
-        Output file path. If not specified, uses input directory name + ".txt"
+        Output file path. If not specified, uses input directory name + ".txt".
+        Use "-" to write to standard output
+  -stdin-list
+        Read newline-separated file paths from standard input and pack
+        exactly those files, in the given order, instead of walking -indir.
+        Paths are relative to the current directory and must be inside an
+        input directory. Missing files, directories and files with unknown
+        extensions are reported on stderr and skipped
+  -sort
+        With -stdin-list, sort the files by path instead of keeping the
+        given order
+  -no-ignore
+        With -stdin-list, don't apply .gitignore, .codepackerignore and
+        -ignore-file rules to the listed files
+  -ignore-file path
+        Additional ignore file in .gitignore syntax, applied to every input
+        directory after .gitignore and .codepackerignore
+  -lang .ext=prepend[,append]
+        Add or override the comment markers for an extension (repeatable),
+        e.g. -lang .proto=// or -lang '.css=/*,*/'
+  -lang-file path
+        JSON file mapping extensions to comment markers, e.g.
+        {".proto": {"prepend": "//"}, ".css": {"prepend": "/*", "append": "*/"}}.
+        -lang entries take precedence over the file
+  -include pattern
+        Only pack files matching the glob pattern (repeatable or comma-separated)
+  -exclude pattern
+        Do not pack files matching the glob pattern (repeatable or comma-separated)
+  -max-file-size size
+        Skip files larger than size bytes. Accepts k, M and G suffixes
+        (e.g. 500k, 2M). 0 means unlimited (default 0)
+  -max-output-size size
+        Split the output into numbered parts of at most size bytes
+        (e.g. codepack.001.txt, codepack.002.txt). Accepts k, M and G
+        suffixes. A file is never split between parts; a file larger than
+        size on its own gets an oversized part of its own (default 0, no limit)
+  -follow-symlinks
+        Descend into symlinked directories and pack symlinked files instead
+        of skipping them. Only links to directories outside the input
+        directory are followed, and every directory is walked once, so a
+        link back to a parent is skipped
+  -detect-shebang
+        Pack files without a known extension whose first line is a shebang
+        naming a known interpreter (e.g. #!/usr/bin/env python3), using that
+        language's comment markers. Only executable files and files of up
+        to 64k are checked
+  -allow-binary
+        Pack files with a code extension even if their content looks binary
+        or isn't valid UTF-8
+  -transcode encoding
+        Convert files to UTF-8 instead of skipping them: "utf16" converts
+        files starting with a UTF-16 byte order mark, "latin1" additionally
+        reads every other file that isn't valid UTF-8 as Latin-1
+  -path-style string
+        How file paths are shown in headers and the manifest: "relative"
+        (src/main.go), "with-root" (myproject/src/main.go) or "absolute"
+        (default "with-root")
+  -dry-run
+        List the files that would be packed with their sizes and a total on
+        stdout, without creating or touching the output file
+  -manifest
+        Start the output with a list of every packed file and its size
+  -strip-comments
+        Drop full-line comments (lines starting with the language's comment
+        marker) from packed files. Lines inside multi-line strings are kept
+        for Go, JavaScript, TypeScript, Python, Java, Kotlin, Scala, Swift,
+        Dart, Groovy and Lua only
+  -strip-blank
+        Collapse consecutive blank lines in packed files into one
+  -line-numbers
+        Prefix every line of packed file contents with its line number
+  -dedupe
+        Replace files whose content was already packed (copies, hardlinks)
+        with a one-line "duplicate of <earlier path>" header
+  -jobs int
+        Number of files read concurrently (default: number of CPUs)
   -verbose
         Enable verbose output
   -force
-        Force overwrite of existing output file
+        Force overwrite of existing output file. With -max-output-size,
+        all parts of an earlier run are removed first
+  -allow-empty
+        Exit with status 0 even if no code files were packed. Without it,
+        a run that packs nothing reports "No code files matched" and fails
+  -append
+        Append to the output file instead of overwriting it, creating it if
+        needed. Doesn't need -force. Not supported with "-outfile -",
+        -max-output-size or -format json
+  -format string
+        Output format: "text" (comment headers), "markdown" (fenced code
+        blocks) or "json" (a single JSON document with path, language, size
+        and content of every file, plus totals) (default "text")
   -help
         Show this help message
 
 Example:
   codepacker -indir ./myproject -outfile output.txt -verbose
   codepacker -indir /path/to/code/project -force
+  codepacker -indir . -outfile - | less
+  git diff --name-only | codepacker -stdin-list -outfile changes.txt
 
 The program will:
 1. Walk through all files in the input directory
-2. Identify code files by their extensions
-3. Add appropriate comment markers for each language
-4. Concatenate all code files into a single output file`
-
-// Maximum path length varies by OS, adding extra bytes for comment markers and newlines
-// Windows MAX_PATH is 260, Unix typically 4096
-const maxBufferSize = 4096 + 100 // path length + extra space for comments and formatting
-
-// CommentStyle defines the structure for comment syntax
-type CommentStyle struct {
-	Prepend string // Opening/starting comment symbol
-	Append  string // Closing comment symbol (if needed)
-}
-
-// GitIgnore holds the ignore patterns and their base directory
-type GitIgnore struct {
-	patterns []string
-	baseDir  string
-}
+2. Skip files matched by .gitignore (including .gitignore files in
+   subdirectories), .codepackerignore, -ignore-file or -exclude, or not
+   matched by -include
+3. Identify code files by their extensions
+4. Add appropriate comment markers for each language
+5. Concatenate all code files into a single output file
+
+Include/exclude patterns are matched against the path relative to the input
+directory. A pattern without "/" matches any path component (e.g. "*.go"),
+a pattern with "/" matches the relative path or one of its parent
+directories (e.g. "internal/*"). A file has to pass the .gitignore rules
+and match at least one -include pattern (if any are given); -exclude always
+wins over -include. "**" is not supported; "internal" or "internal/*"
+already covers everything below internal, and "*.go" matches Go files at
+any depth.`
 
-// LoadGitIgnore loads .gitignore files from the given directory and its parents
-func LoadGitIgnore(dir string) (*GitIgnore, error) {
-	patterns := make([]string, 0)
+func main() {
+	// Get cmd line arguments using flags
+	var indirs stringList
+	flag.Var(&indirs, "indir", "Input directory (repeatable or comma-separated)")
+	outfile := flag.String("outfile", "", "Output file")
+	stdinList := flag.Bool("stdin-list", false, "Pack the files listed on stdin instead of walking -indir")
+	sortList := flag.Bool("sort", false, "Sort the -stdin-list files by path")
+	noIgnore := flag.Bool("no-ignore", false, "Don't apply ignore rules to the -stdin-list files")
+	ignoreFile := flag.String("ignore-file", "", "Additional ignore file in .gitignore syntax")
+	var langs repeatedList
+	flag.Var(&langs, "lang", "Comment markers for an extension as .ext=prepend[,append] (repeatable)")
+	langFile := flag.String("lang-file", "", "JSON file mapping extensions to comment markers")
+	var includes, excludes stringList
+	flag.Var(&includes, "include", "Only pack files matching glob pattern (repeatable)")
+	flag.Var(&excludes, "exclude", "Do not pack files matching glob pattern (repeatable)")
+	var maxFileSize byteSize
+	flag.Var(&maxFileSize, "max-file-size", "Skip files larger than size (e.g. 500k, 2M)")
+	var maxOutputSize byteSize
+	flag.Var(&maxOutputSize, "max-output-size", "Split output into numbered parts of at most size (e.g. 400k)")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Follow symlinks to files and directories")
+	detectShebang := flag.Bool("detect-shebang", false, "Recognize extensionless scripts by their shebang line")
+	allowBinary := flag.Bool("allow-binary", false, "Pack files even if their content looks binary")
+	transcode := flag.String("transcode", "", "Convert files from this encoding to UTF-8 (utf16 or latin1)")
+	pathStyle := flag.String("path-style", codepacker.PathStyleWithRoot, "Path shown in headers (relative, with-root or absolute)")
+	dryRun := flag.Bool("dry-run", false, "List files that would be packed without writing output")
+	manifest := flag.Bool("manifest", false, "List packed files at the top of the output")
+	stripComments := flag.Bool("strip-comments", false, "Drop full-line comments from file contents")
+	stripBlank := flag.Bool("strip-blank", false, "Collapse consecutive blank lines in file contents")
+	lineNumbers := flag.Bool("line-numbers", false, "Prefix every line of file contents with its line number")
+	dedupe := flag.Bool("dedupe", false, "Replace files with already packed content by a reference")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "Number of files read concurrently")
+	verbose := flag.Bool("verbose", false, "Verbose output")
+	force := flag.Bool("force", false, "Force overwrite output file")
+	allowEmpty := flag.Bool("allow-empty", false, "Exit successfully even if no files are packed")
+	appendOutput := flag.Bool("append", false, "Append to the output file instead of overwriting it")
+	format := flag.String("format", codepacker.FormatText, "Output format (text, markdown or json)")
+	help := flag.Bool("help", false, "Show help message")
 
-	// Start from the given directory and move up until we find a .git folder or reach root
-	currentDir := dir
-	for {
-		gitignorePath := filepath.Join(currentDir, ".gitignore")
-		if _, err := os.Stat(gitignorePath); err == nil {
-			file, err := os.Open(gitignorePath)
-			if err != nil {
-				return nil, fmt.Errorf("error opening .gitignore: %v", err)
-			}
-			defer file.Close()
-
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				// Skip empty lines and comments
-				if line != "" && !strings.HasPrefix(line, "#") {
-					patterns = append(patterns, line)
-				}
-			}
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n", helpText)
+	}
 
-			if scanner.Err() != nil {
-				return nil, fmt.Errorf("error reading .gitignore: %v", scanner.Err())
-			}
-		}
+	flag.Parse()
 
-		// Check if we're in a git repository
-		if _, err := os.Stat(filepath.Join(currentDir, ".git")); err == nil {
-			// Found the repository root, stop here
-			return &GitIgnore{
-				patterns: patterns,
-				baseDir:  currentDir,
-			}, nil
-		}
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
 
-		// Move up one directory
-		parentDir := filepath.Dir(currentDir)
-		if parentDir == currentDir {
-			// We've reached the root directory
-			break
-		}
-		currentDir = parentDir
+	if *jobs < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid -jobs value %d. It must be at least 1.\n", *jobs)
+		os.Exit(1)
 	}
 
-	// If we didn't find a .git directory, just use the patterns we found (if any)
-	return &GitIgnore{
-		patterns: patterns,
-		baseDir:  dir,
-	}, nil
-}
+	if (*sortList || *noIgnore) && !*stdinList {
+		fmt.Fprintf(os.Stderr, "-sort and -no-ignore can only be used with -stdin-list.\n")
+		os.Exit(1)
+	}
 
-// ShouldIgnore checks if a path should be ignored based on gitignore patterns
-func (gi *GitIgnore) ShouldIgnore(path string) bool {
-	// Convert path to be relative to the base directory
-	relPath, err := filepath.Rel(gi.baseDir, path)
+	commentStyles, err := buildCommentStyles(*langFile, langs)
 	if err != nil {
-		return false
-	}
-
-	// Common directories to ignore even if not in .gitignore
-	commonIgnores := []string{
-		"node_modules",
-		"vendor",
-		"build",
-		"dist",
-		"target",
-		"bin",
-		"obj",
-		".git",
-		".idea",
-		".vscode",
-		".zig-cache",
-		"zig-out",
-		"__pycache__",
-		".pytest_cache",
-		".mypy_cache",
-	}
-
-	// Check common ignores first
-	pathParts := strings.Split(relPath, string(filepath.Separator))
-	for _, part := range pathParts {
-		for _, ignore := range commonIgnores {
-			if part == ignore {
-				return true
-			}
-		}
+		fmt.Fprintf(os.Stderr, "Error in language mappings: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Check each gitignore pattern
-	for _, pattern := range gi.patterns {
-		matched, err := filepath.Match(pattern, relPath)
-		if err == nil && matched {
-			return true
-		}
+	opts := codepacker.Options{
+		InputDirs:      indirs,
+		SortFiles:      *sortList,
+		NoIgnore:       *noIgnore,
+		IgnoreFile:     *ignoreFile,
+		Includes:       includes,
+		Excludes:       excludes,
+		Format:         *format,
+		PathStyle:      *pathStyle,
+		Manifest:       *manifest,
+		DryRun:         *dryRun,
+		MaxFileSize:    int64(maxFileSize),
+		FollowSymlinks: *followSymlinks,
+		DetectShebang:  *detectShebang,
+		AllowBinary:    *allowBinary,
+		Transcode:      *transcode,
+		StripComments:  *stripComments,
+		StripBlank:     *stripBlank,
+		LineNumbers:    *lineNumbers,
+		Dedupe:         *dedupe,
+		Jobs:           *jobs,
+		CommentStyles:  commentStyles,
+	}
+	if *verbose {
+		// Diagnostics go to stderr so they never mix with output piped from stdout
+		opts.Log = os.Stderr
+	}
+	opts.Warn = os.Stderr
 
-		// Handle directory wildcards (e.g., **/node_modules)
-		if strings.Contains(pattern, "**") {
-			pattern = strings.ReplaceAll(pattern, "**", "*")
-			for _, part := range pathParts {
-				matched, err := filepath.Match(pattern, part)
-				if err == nil && matched {
-					return true
-				}
-			}
+	if *stdinList {
+		files, err := readFileList(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file list from stdin: %v\n", err)
+			os.Exit(1)
 		}
+		opts.Files = files
 	}
 
-	return false
-}
-
-// TreeNode represents a node in the file tree
-type TreeNode struct {
-	name     string
-	isDir    bool
-	children map[string]*TreeNode
-}
+	// Reject bad options before the output file is created or truncated
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-// NewTreeNode creates a new tree node
-func NewTreeNode(name string, isDir bool) *TreeNode {
-	return &TreeNode{
-		name:     name,
-		isDir:    isDir,
-		children: make(map[string]*TreeNode),
+	// Name what was searched in case nothing matches
+	source := strings.Join(indirs, ", ")
+	if *stdinList {
+		source = "the files listed on stdin"
+	} else if len(indirs) == 0 {
+		source = "."
 	}
-}
 
-// GenerateTreeView creates a tree view of the directory structure
-func GenerateTreeView(root string) (string, error) {
-	tree := NewTreeNode("", true)
+	// A dry run reports to stdout and never creates or truncates the output file
+	if *dryRun {
+		stats, err := codepacker.Pack(opts, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		checkPacked(stats, source, *allowEmpty)
+		return
+	}
 
-	// Load gitignore patterns
-	gitignore, err := LoadGitIgnore(root)
+	// Get current working directory for output file
+	cwd, err := os.Getwd()
 	if err != nil {
-		// Continue without gitignore if there's an error, but don't return the error
-		gitignore = &GitIgnore{patterns: []string{}, baseDir: root}
+		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+		os.Exit(1)
 	}
 
-	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// Handle output file path - always in current working directory, "-" means stdout
+	toStdout := *outfile == "-"
+	var outfilepath string
+	if toStdout {
+		outfilepath = "stdout"
+	} else if *outfile == "" {
+		outfilepath = filepath.Join(cwd, "codepack.txt")
+	} else {
+		outfilepath = filepath.Join(cwd, *outfile)
+	}
+
+	if *appendOutput {
+		switch {
+		case toStdout:
+			fmt.Fprintf(os.Stderr, "-append cannot be used when writing to stdout.\n")
+			os.Exit(1)
+		case maxOutputSize > 0:
+			fmt.Fprintf(os.Stderr, "-append cannot be combined with -max-output-size.\n")
+			os.Exit(1)
+		case *format == codepacker.FormatJSON:
+			fmt.Fprintf(os.Stderr, "-append cannot be used with -format json, the result would not be a valid JSON document.\n")
+			os.Exit(1)
 		}
+	}
 
-		// Check if path should be ignored based on gitignore rules
-		if gitignore != nil && gitignore.ShouldIgnore(path) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+	if maxOutputSize > 0 {
+		if toStdout {
+			fmt.Fprintf(os.Stderr, "-max-output-size cannot be used when writing to stdout.\n")
+			os.Exit(1)
 		}
 
-		relPath, err := filepath.Rel(root, path)
+		if *verbose {
+			println("Output files:", partPath(outfilepath, 1), "...")
+		}
+		// Check for parts of an earlier run before writing any, the number of
+		// parts this run writes isn't known up front
+		oldParts, err := existingParts(outfilepath)
 		if err != nil {
-			return err
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-
-		// Skip root directory
-		if relPath == "." {
-			return nil
+		if len(oldParts) > 0 && !*force {
+			fmt.Fprintf(os.Stderr, "Output file %s already exists. Use -force to overwrite.\n", oldParts[0])
+			os.Exit(1)
 		}
-
-		parts := strings.Split(relPath, string(filepath.Separator))
-		current := tree
-
-		// Build tree structure
-		for i, part := range parts {
-			isLast := i == len(parts)-1
-			if _, exists := current.children[part]; !exists {
-				current.children[part] = NewTreeNode(part, !isLast || info.IsDir())
+		// Remove all of them, so that no part of a longer earlier run is left behind
+		for _, name := range oldParts {
+			if *verbose {
+				println("Removing old part", name)
+			}
+			if err := os.Remove(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error removing old output file: %v\n", err)
+				os.Exit(1)
 			}
-			current = current.children[part]
 		}
 
-		return nil
-	})
-
-	if err != nil {
-		return "", err
+		create := func(part int) (io.WriteCloser, error) {
+			name := partPath(outfilepath, part)
+			if *verbose {
+				println("Writing part", part, "to", name)
+			}
+			f, err := os.Create(name)
+			if err != nil {
+				return nil, fmt.Errorf("error creating output file: %v", err)
+			}
+			return f, nil
+		}
+		stats, err := codepacker.PackSplit(opts, int64(maxOutputSize), create)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		into := partPath(outfilepath, 1)
+		if stats.Parts > 1 {
+			into = fmt.Sprintf("%d parts (%s to %s)", stats.Parts, into, partPath(outfilepath, stats.Parts))
+		}
+		printSummary(stats, into)
+		checkPacked(stats, source, *allowEmpty)
+		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString("Project Structure:\n")
-	printTree(tree, "", true, &sb)
-	return sb.String(), nil
-}
+	// Check if output file exists, appending to it is fine
+	if !*force && !*appendOutput && !toStdout {
+		if _, err := os.Stat(outfilepath); err == nil {
+			fmt.Fprintf(os.Stderr, "Output file already exists. Use -force to overwrite.\n")
+			os.Exit(1)
+		}
+	}
 
-// printTree recursively prints the tree structure
-func printTree(node *TreeNode, prefix string, isLast bool, sb *strings.Builder) {
-	if node.name != "" {
-		sb.WriteString(prefix)
-		if isLast {
-			sb.WriteString("└── ")
-			prefix += "    "
+	if *verbose {
+		if *appendOutput {
+			println("Appending to output file:", outfilepath)
 		} else {
-			sb.WriteString("├── ")
-			prefix += "│   "
+			println("Output file:", outfilepath)
 		}
-		sb.WriteString(node.name)
-		sb.WriteString("\n")
 	}
 
-	// Sort children for consistent output
-	children := make([]string, 0, len(node.children))
-	for name := range node.children {
-		children = append(children, name)
+	// Create output file unless streaming to stdout
+	var out io.Writer = os.Stdout
+	if *appendOutput {
+		f, err := openForAppend(outfilepath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	} else if !toStdout {
+		f, err := os.Create(outfilepath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
 	}
-	sort.Strings(children)
 
-	for i, name := range children {
-		child := node.children[name]
-		isLast := i == len(children)-1
-		printTree(child, prefix, isLast, sb)
+	stats, err := codepacker.Pack(opts, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	printSummary(stats, outfilepath)
+	checkPacked(stats, source, *allowEmpty)
 }
 
-func main() {
-	// Get cmd line arguments using flags
-	indir := flag.String("indir", ".", "Input directory")
-	outfile := flag.String("outfile", "", "Output file")
-	verbose := flag.Bool("verbose", false, "Verbose output")
-	force := flag.Bool("force", false, "Force overwrite output file")
-	help := flag.Bool("help", false, "Show help message")
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "%s\n", helpText)
-	}
-
-	flag.Parse()
+// printSummary reports on stderr what a run packed, so piped output stays clean
+func printSummary(stats codepacker.Stats, into string) {
+	fmt.Fprintf(os.Stderr, "Packed %d files, %d bytes into %s\n", stats.Files, stats.Bytes, into)
+}
 
-	if *help {
-		flag.Usage()
-		os.Exit(0)
+// checkPacked exits with an error if no file was packed from source, which
+// usually means a mistyped -indir or filters that exclude everything
+func checkPacked(stats codepacker.Stats, source string, allowEmpty bool) {
+	if stats.Files == 0 && !allowEmpty {
+		fmt.Fprintf(os.Stderr, "No code files matched in %s. Use -allow-empty to accept empty output.\n", source)
+		os.Exit(1)
 	}
+}
 
-	// Clean and resolve the input directory path
-	cleanInDir := filepath.Clean(*indir)
-	absdir, err := filepath.Abs(cleanInDir)
+// openForAppend opens path for appending, creating it if needed. Packed output
+// ends with a blank line, so existing content that doesn't is completed with
+// the missing newlines to keep the appended blocks separated from it.
+func openForAppend(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving input directory path: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	// Get current working directory for output file
-	cwd, err := os.Getwd()
+	info, err := f.Stat()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
-		os.Exit(1)
+		f.Close()
+		return nil, err
 	}
-
-	// Handle output file path - always in current working directory
-	var outfilepath string
-	if *outfile == "" {
-		outfilepath = filepath.Join(cwd, "codepack.txt")
-	} else {
-		outfilepath = filepath.Join(cwd, *outfile)
+	if size := info.Size(); size > 0 {
+		tail := make([]byte, min(size, 2))
+		if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil {
+			f.Close()
+			return nil, err
+		}
+		sep := "\n\n"
+		if bytes.HasSuffix(tail, []byte("\n\n")) || (size == 1 && tail[0] == '\n') {
+			sep = ""
+		} else if tail[len(tail)-1] == '\n' {
+			sep = "\n"
+		}
+		if _, err := f.WriteString(sep); err != nil {
+			f.Close()
+			return nil, err
+		}
 	}
+	return f, nil
+}
 
-	// Check if output file exists
-	if !*force {
-		if _, err := os.Stat(outfilepath); err == nil {
-			fmt.Fprintf(os.Stderr, "Output file already exists. Use -force to overwrite.\n")
-			os.Exit(1)
+// stringList is a flag.Value that collects repeated and comma-separated flag values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
 		}
 	}
+	return nil
+}
 
-	if *verbose {
-		println("Input directory:", absdir)
-		println("Output file:", outfilepath)
+// readFileList reads newline-separated file paths from r, skipping blank lines.
+// The result is never nil, so an empty list packs no files instead of walking.
+func readFileList(r io.Reader) ([]string, error) {
+	files := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Tolerate lists written with CRLF line endings
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) != "" {
+			files = append(files, line)
+		}
 	}
+	return files, scanner.Err()
+}
 
-	// Create output file
-	f, err := os.Create(outfilepath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
-		os.Exit(1)
-	}
-	defer f.Close()
+// partPath returns the name of part n of a split output, inserting a
+// zero-padded part number before the extension (codepack.001.txt)
+func partPath(outfilepath string, n int) string {
+	ext := filepath.Ext(outfilepath)
+	return fmt.Sprintf("%s.%03d%s", strings.TrimSuffix(outfilepath, ext), n, ext)
+}
 
-	// Generate and write tree view
-	treeView, err := GenerateTreeView(absdir)
+// existingParts returns the paths of the existing parts of a split output to
+// outfilepath, i.e. files named like partPath would name them, in name order
+func existingParts(outfilepath string) ([]string, error) {
+	dir := filepath.Dir(outfilepath)
+	ext := filepath.Ext(outfilepath)
+	prefix := strings.TrimSuffix(filepath.Base(outfilepath), ext) + "."
+
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating tree view: %v\n", err)
-		os.Exit(1)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading output directory: %v", err)
 	}
-	if _, err := f.WriteString(treeView + "\n\n"); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing tree view: %v\n", err)
-		os.Exit(1)
+
+	var parts []string
+	for _, entry := range entries {
+		num, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		num, ok = strings.CutSuffix(num, ext)
+		// Part numbers have at least three digits
+		if !ok || len(num) < 3 || strings.Trim(num, "0123456789") != "" {
+			continue
+		}
+		parts = append(parts, filepath.Join(dir, entry.Name()))
 	}
+	return parts, nil
+}
 
-	sb := strings.Builder{}
-	sb.Grow(maxBufferSize)
+// repeatedList is a flag.Value that collects repeated flag values as given
+type repeatedList []string
 
-	err = filepath.Walk(absdir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+func (l *repeatedList) String() string {
+	return strings.Join(*l, " ")
+}
 
-		// Skip .gitignore files
-		if info.Name() == ".gitignore" {
-			return nil
-		}
+func (l *repeatedList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-		if info.IsDir() {
-			return nil
-		}
-		if info.Mode()&os.ModeSymlink != 0 {
-			return nil
-		}
+// buildCommentStyles returns a copy of codepacker.FileExtToComment extended with the
+// mappings from langFile (if set) and then the -lang specs, later ones
+// overriding earlier ones
+func buildCommentStyles(langFile string, specs []string) (map[string]codepacker.CommentStyle, error) {
+	styles := make(map[string]codepacker.CommentStyle, len(codepacker.FileExtToComment))
+	for ext, style := range codepacker.FileExtToComment {
+		styles[ext] = style
+	}
 
-		ext := filepath.Ext(path)
-		commentStyle, ok := FileExtToComment[ext]
-		if !ok {
-			if *verbose {
-				fmt.Println("Skipping (not a code file):", path)
-			}
-			return nil
+	if langFile != "" {
+		data, err := os.ReadFile(langFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading language file: %v", err)
 		}
-
-		code := readCodeFile(path)
-		if code == nil {
-			if *verbose {
-				fmt.Println("Skipping (empty file):", path)
-			}
-			return nil
+		var fileStyles map[string]codepacker.CommentStyle
+		if err := json.Unmarshal(data, &fileStyles); err != nil {
+			return nil, fmt.Errorf("error parsing language file %s: %v", langFile, err)
 		}
-
-		if *verbose {
-			fmt.Println("Processing:", path)
+		for ext, style := range fileStyles {
+			if err := validateCommentStyle(ext, style); err != nil {
+				return nil, fmt.Errorf("%s: %v", langFile, err)
+			}
+			styles[ext] = style
 		}
+	}
 
-		// Calculate path relative to indir, keep the directory structure
-		relPath, err := filepath.Rel(absdir, path)
+	for _, spec := range specs {
+		ext, style, err := parseLangSpec(spec)
 		if err != nil {
-			return fmt.Errorf("error getting relative path: %v", err)
+			return nil, err
 		}
+		styles[ext] = style
+	}
 
-		sb.Reset()
-		sb.WriteString(commentStyle.Prepend)
-		sb.WriteString(" ")
+	return styles, nil
+}
 
-		// Add the input directory name as prefix to maintain context
-		dirName := filepath.Base(absdir)
-		sb.WriteString(filepath.Join(dirName, relPath))
+// parseLangSpec parses a -lang value of the form .ext=prepend[,append]
+func parseLangSpec(spec string) (string, codepacker.CommentStyle, error) {
+	ext, markers, ok := strings.Cut(spec, "=")
+	if !ok {
+		return "", codepacker.CommentStyle{}, fmt.Errorf("invalid -lang %q: expected .ext=prepend[,append]", spec)
+	}
 
-		sb.WriteString(" ")
-		sb.WriteString(commentStyle.Append)
-		sb.WriteString("\n")
+	prepend, appendMarker, _ := strings.Cut(markers, ",")
+	style := codepacker.CommentStyle{
+		Prepend: strings.TrimSpace(prepend),
+		Append:  strings.TrimSpace(appendMarker),
+	}
+	ext = strings.TrimSpace(ext)
+	if err := validateCommentStyle(ext, style); err != nil {
+		return "", codepacker.CommentStyle{}, fmt.Errorf("invalid -lang %q: %v", spec, err)
+	}
+	return ext, style, nil
+}
 
-		if _, err := f.WriteString(sb.String()); err != nil {
-			return fmt.Errorf("error writing to output file: %v", err)
-		}
-		if _, err := f.Write(code); err != nil {
-			return fmt.Errorf("error writing code to output file: %v", err)
-		}
-		if _, err := f.WriteString("\n\n"); err != nil {
-			return fmt.Errorf("error writing newlines to output file: %v", err)
-		}
+// validateCommentStyle checks a user supplied extension and its comment markers
+func validateCommentStyle(ext string, style codepacker.CommentStyle) error {
+	if len(ext) < 2 || ext[0] != '.' {
+		return fmt.Errorf("extension %q must start with a dot, e.g. .proto", ext)
+	}
+	if strings.ContainsAny(ext, "/\\ \t") || strings.Contains(ext[1:], ".") {
+		return fmt.Errorf("extension %q must be a single extension like .proto", ext)
+	}
+	if strings.TrimSpace(style.Prepend) == "" {
+		return fmt.Errorf("extension %q needs a non-empty comment prefix", ext)
+	}
+	return nil
+}
 
-		return nil
-	})
+// byteSize is a flag.Value holding a size in bytes parsed with parseSize
+type byteSize int64
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error walking directory: %v\n", err)
-		os.Exit(1)
-	}
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
 }
 
-func readCodeFile(path string) []byte {
-	f, err := os.Open(path)
+func (b *byteSize) Set(value string) error {
+	n, err := parseSize(value)
 	if err != nil {
-		return nil
+		return err
 	}
-	defer f.Close()
+	*b = byteSize(n)
+	return nil
+}
 
-	b, err := io.ReadAll(f)
-	if err != nil {
-		return nil
+// parseSize parses a size in bytes with an optional k, M or G suffix (powers of 1024).
+// The suffix is case-insensitive and may be followed by "b", as in "500kb".
+func parseSize(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	s = strings.TrimSuffix(s, "b")
+
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
 	}
 
-	return b
-}
-
-var FileExtToComment = map[string]CommentStyle{
-	// C and C-like languages
-	".c":   {Prepend: "//", Append: ""},
-	".h":   {Prepend: "//", Append: ""},
-	".cpp": {Prepend: "//", Append: ""},
-	".hpp": {Prepend: "//", Append: ""},
-	".cc":  {Prepend: "//", Append: ""},
-	".hh":  {Prepend: "//", Append: ""},
-	".cxx": {Prepend: "//", Append: ""},
-	".cs":  {Prepend: "//", Append: ""}, // C#
-
-	// Web development
-	".js":   {Prepend: "//", Append: ""},   // JavaScript
-	".jsx":  {Prepend: "//", Append: ""},   // React JSX
-	".ts":   {Prepend: "//", Append: ""},   // TypeScript
-	".tsx":  {Prepend: "//", Append: ""},   // TypeScript React
-	".php":  {Prepend: "//", Append: ""},   // PHP (also supports #)
-	".css":  {Prepend: "/*", Append: "*/"}, // CSS
-	".scss": {Prepend: "//", Append: ""},   // SASS
-	".less": {Prepend: "//", Append: ""},   // LESS
-
-	// System/Shell scripting
-	".sh":   {Prepend: "#", Append: ""}, // Shell script
-	".bash": {Prepend: "#", Append: ""}, // Bash script
-	".zsh":  {Prepend: "#", Append: ""}, // Zsh script
-	".fish": {Prepend: "#", Append: ""}, // Fish script
-	".ksh":  {Prepend: "#", Append: ""}, // Korn shell
-	".ps1":  {Prepend: "#", Append: ""}, // PowerShell
-	".psm1": {Prepend: "#", Append: ""}, // PowerShell module
-
-	// Modern languages
-	".go":    {Prepend: "//", Append: ""}, // Go
-	".rs":    {Prepend: "//", Append: ""}, // Rust
-	".dart":  {Prepend: "//", Append: ""}, // Dart
-	".swift": {Prepend: "//", Append: ""}, // Swift
-	".kt":    {Prepend: "//", Append: ""}, // Kotlin
-	".scala": {Prepend: "//", Append: ""}, // Scala
-	".zig":   {Prepend: "//", Append: ""},
-
-	// Traditional languages
-	".java":   {Prepend: "//", Append: ""}, // Java
-	".groovy": {Prepend: "//", Append: ""}, // Groovy
-	".rb":     {Prepend: "#", Append: ""},  // Ruby
-	".py":     {Prepend: "#", Append: ""},  // Python
-	".pl":     {Prepend: "#", Append: ""},  // Perl
-	".pm":     {Prepend: "#", Append: ""},  // Perl module
-	".lua":    {Prepend: "--", Append: ""}, // Lua
-	".tcl":    {Prepend: "#", Append: ""},  // Tcl
-
-	// Configuration and markup
-	".yaml": {Prepend: "#", Append: ""},       // YAML
-	".yml":  {Prepend: "#", Append: ""},       // YAML
-	".toml": {Prepend: "#", Append: ""},       // TOML
-	".ini":  {Prepend: ";", Append: ""},       // INI
-	".conf": {Prepend: "#", Append: ""},       // Config files
-	".xml":  {Prepend: "<!--", Append: "-->"}, // XML
-	".html": {Prepend: "<!--", Append: "-->"}, // HTML
-
-	// Database
-	".sql":   {Prepend: "--", Append: ""}, // SQL
-	".psql":  {Prepend: "--", Append: ""}, // PostgreSQL
-	".mysql": {Prepend: "--", Append: ""}, // MySQL
-
-	// Other
-	".r":   {Prepend: "#", Append: ""},    // R
-	".jl":  {Prepend: "#", Append: ""},    // Julia
-	".fs":  {Prepend: "//", Append: ""},   // F#
-	".fsx": {Prepend: "//", Append: ""},   // F# script
-	".f90": {Prepend: "!", Append: ""},    // Fortran
-	".f95": {Prepend: "!", Append: ""},    // Fortran
-	".f":   {Prepend: "!", Append: ""},    // Fortran
-	".elm": {Prepend: "--", Append: ""},   // Elm
-	".ex":  {Prepend: "#", Append: ""},    // Elixir
-	".exs": {Prepend: "#", Append: ""},    // Elixir script
-	".erl": {Prepend: "%", Append: ""},    // Erlang
-	".hrl": {Prepend: "%", Append: ""},    // Erlang header
-	".hs":  {Prepend: "--", Append: ""},   // Haskell
-	".lhs": {Prepend: "--", Append: ""},   // Literate Haskell
-	".ml":  {Prepend: "(*", Append: "*)"}, // OCaml
-	".mli": {Prepend: "(*", Append: "*)"}, // OCaml interface
-	".v":   {Prepend: "//", Append: ""},   // Verilog
-	".vh":  {Prepend: "//", Append: ""},   // Verilog header
-	".vhd": {Prepend: "--", Append: ""},   // VHDL
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return n * multiplier, nil
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/helshabini/codepacker/codepacker"
+)
+
+func TestExistingParts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"out.001.txt", "out.002.txt", "out.1000.txt", "out.txt", "out.01.txt", "out.abc.txt", "out.003.md", "other.001.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := existingParts(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, path := range got {
+		names = append(names, filepath.Base(path))
+	}
+	if want := []string{"out.001.txt", "out.002.txt", "out.1000.txt"}; !slices.Equal(names, want) {
+		t.Errorf("existingParts() = %q, want %q", names, want)
+	}
+
+	if got, err := existingParts(filepath.Join(dir, "missing", "out.txt")); err != nil || got != nil {
+		t.Errorf("existingParts() in a missing directory = %q, %v, want none", got, err)
+	}
+}
+
+func TestParseLangSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		ext     string
+		style   codepacker.CommentStyle
+		wantErr bool
+	}{
+		{spec: ".proto=//", ext: ".proto", style: codepacker.CommentStyle{Prepend: "//"}},
+		{spec: ".css=/*,*/", ext: ".css", style: codepacker.CommentStyle{Prepend: "/*", Append: "*/"}},
+		{spec: " .sql = -- ", ext: ".sql", style: codepacker.CommentStyle{Prepend: "--"}},
+		{spec: "proto=//", wantErr: true},
+		{spec: ".tar.gz=#", wantErr: true},
+		{spec: ".=#", wantErr: true},
+		{spec: ".x=", wantErr: true},
+		{spec: ".x= ,*/", wantErr: true},
+		{spec: ".x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ext, style, err := parseLangSpec(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseLangSpec(%q) = %q, %+v, want an error", tt.spec, ext, style)
+				}
+				return
+			}
+			if err != nil || ext != tt.ext || style != tt.style {
+				t.Errorf("parseLangSpec(%q) = %q, %+v, %v, want %q, %+v", tt.spec, ext, style, err, tt.ext, tt.style)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "123", want: 123},
+		{value: "500k", want: 500 << 10},
+		{value: "2M", want: 2 << 20},
+		{value: "1kb", want: 1 << 10},
+		{value: "1G", want: 1 << 30},
+		{value: "0", want: 0},
+		{value: "k", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "10x", wantErr: true},
+		{value: "9223372036854775807k", wantErr: true},
+		{value: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSize(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSize(%q) = %d, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenForAppend(t *testing.T) {
+	tests := []struct {
+		name     string
+		exists   bool
+		existing string
+		want     string
+	}{
+		{"new file", false, "", "block\n"},
+		{"no trailing newline", true, "old", "old\n\nblock\n"},
+		{"one trailing newline", true, "old\n", "old\n\nblock\n"},
+		{"two trailing newlines", true, "old\n\n", "old\n\nblock\n"},
+		{"single newline", true, "\n", "\nblock\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			if tt.exists {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := openForAppend(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.WriteString("block\n"); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("appended file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}